@@ -0,0 +1,115 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"time"
+)
+
+// ErrBadPEM is "PEM block is not found" error message.
+var ErrBadPEM = errors.New("PEM block is not found")
+
+// serial returns random serial number for new certificate.
+func serial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// encode returns PEM-encoded certificate and private key.
+func encode(der []byte, key *ecdsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	var kb []byte
+	if kb, err = x509.MarshalECPrivateKey(key); err != nil {
+		return
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})
+	return
+}
+
+// GenerateCA creates self-signed CA certificate with given common name,
+// valid for given duration. Returns PEM-encoded certificate and its private key.
+func GenerateCA(cn string, ttl time.Duration) (certPEM, keyPEM []byte, err error) {
+	var key *ecdsa.PrivateKey
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return
+	}
+	var sn *big.Int
+	if sn, err = serial(); err != nil {
+		return
+	}
+	var now = time.Now()
+	var tmpl = &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(ttl),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key); err != nil {
+		return
+	}
+	return encode(der, key)
+}
+
+// GenerateCert creates certificate with given common name signed by given CA,
+// valid for given duration. Certificate is usable both for server and client
+// authentication, hosts are IP addresses or DNS names for server side.
+// Returns PEM-encoded certificate and its private key.
+func GenerateCert(caCertPEM, caKeyPEM []byte, cn string, hosts []string, ttl time.Duration) (certPEM, keyPEM []byte, err error) {
+	var block *pem.Block
+	if block, _ = pem.Decode(caCertPEM); block == nil {
+		err = ErrBadPEM
+		return
+	}
+	var ca *x509.Certificate
+	if ca, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return
+	}
+	if block, _ = pem.Decode(caKeyPEM); block == nil {
+		err = ErrBadPEM
+		return
+	}
+	var cakey *ecdsa.PrivateKey
+	if cakey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+		return
+	}
+
+	var key *ecdsa.PrivateKey
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return
+	}
+	var sn *big.Int
+	if sn, err = serial(); err != nil {
+		return
+	}
+	var now = time.Now()
+	var tmpl = &x509.Certificate{
+		SerialNumber: sn,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, cakey); err != nil {
+		return
+	}
+	return encode(der, key)
+}
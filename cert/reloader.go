@@ -0,0 +1,197 @@
+// Package cert provides TLS certificates loading with reload on demand,
+// and generation of self-signed certificates for local compositions.
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoCA is "CA file has no any certificate" error message.
+	ErrNoCA = errors.New("CA file has no any certificate")
+	// ErrNoPeer is "peer has not presented any certificate" error message.
+	ErrNoPeer = errors.New("peer has not presented any certificate")
+)
+
+// Reloader keeps TLS certificate and CA pool loaded from files.
+// Configurations produced by reloader always use last loaded
+// certificates, so they can be reloaded without dropping
+// established connections.
+type Reloader struct {
+	CertFile string // PEM file with certificate chain, can be empty
+	KeyFile  string // PEM file with private key of certificate
+	CAFile   string // PEM file with CA certificates, can be empty
+
+	cert *tls.Certificate
+	pool *x509.CertPool
+	mux  sync.RWMutex
+}
+
+// NewReloader creates reloader for given files and loads them.
+func NewReloader(certfile, keyfile, cafile string) (r *Reloader, err error) {
+	r = &Reloader{
+		CertFile: certfile,
+		KeyFile:  keyfile,
+		CAFile:   cafile,
+	}
+	if err = r.Reload(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Reload reads certificate and CA files again.
+// Previous certificates remains in use if any error occurs.
+func (r *Reloader) Reload() (err error) {
+	var cert *tls.Certificate
+	var pool *x509.CertPool
+	if r.CertFile != "" {
+		var c tls.Certificate
+		if c, err = tls.LoadX509KeyPair(r.CertFile, r.KeyFile); err != nil {
+			return
+		}
+		cert = &c
+	}
+	if r.CAFile != "" {
+		var body []byte
+		if body, err = os.ReadFile(r.CAFile); err != nil {
+			return
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(body) {
+			return ErrNoCA
+		}
+	}
+
+	r.mux.Lock()
+	r.cert, r.pool = cert, pool
+	r.mux.Unlock()
+	return
+}
+
+// modtime returns latest modification time of all given files.
+func (r *Reloader) modtime() (mt time.Time) {
+	for _, fpath := range []string{r.CertFile, r.KeyFile, r.CAFile} {
+		if fpath == "" {
+			continue
+		}
+		if fi, err := os.Stat(fpath); err == nil && fi.ModTime().After(mt) {
+			mt = fi.ModTime()
+		}
+	}
+	return
+}
+
+// Watch checks up modification time of files with given period,
+// and reloads certificates when any file was changed.
+// Given callback is called after each reload with its result.
+// Returns when context is done.
+func (r *Reloader) Watch(ctx context.Context, period time.Duration, onload func(error)) {
+	var last = r.modtime()
+	var ticker = time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if mt := r.modtime(); mt.After(last) {
+				last = mt
+				onload(r.Reload())
+			}
+		}
+	}
+}
+
+// Certificate returns last loaded certificate, or nil if it's not given.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return r.cert
+}
+
+// Pool returns last loaded CA pool, or nil if it's not given.
+func (r *Reloader) Pool() *x509.CertPool {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return r.pool
+}
+
+// GetCertificate is tls.Config callback for server side.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate is tls.Config callback for client side.
+// Returns empty certificate if it's not given, so server
+// will decide whether to continue the handshake.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert := r.Certificate(); cert != nil {
+		return cert, nil
+	}
+	return &tls.Certificate{}, nil
+}
+
+// ServerConfig returns TLS configuration for server side.
+// If CA file is given, clients must present certificate
+// signed by this CA (mutual TLS).
+func (r *Reloader) ServerConfig() *tls.Config {
+	var cfg = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+	if r.CAFile != "" {
+		// Verification is performed at VerifyConnection callback
+		// to use always last loaded CA pool.
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return r.verify(cs, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return cfg
+}
+
+// ClientConfig returns TLS configuration for client side.
+// Server certificate is verified by CA if CA file is given,
+// or by system pool otherwise. Client certificate is presented
+// to server if it's given.
+func (r *Reloader) ClientConfig() *tls.Config {
+	var cfg = &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: r.GetClientCertificate,
+	}
+	if r.CAFile != "" {
+		// Verification is performed at VerifyConnection callback
+		// to use always last loaded CA pool, see example at
+		// https://pkg.go.dev/crypto/tls#example-Config-VerifyConnection
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return r.verify(cs, cs.ServerName, x509.ExtKeyUsageServerAuth)
+		}
+	}
+	return cfg
+}
+
+// verify checks up peer certificates chain by last loaded CA pool.
+func (r *Reloader) verify(cs tls.ConnectionState, dnsname string, usage x509.ExtKeyUsage) error {
+	if len(cs.PeerCertificates) == 0 {
+		return ErrNoPeer
+	}
+	var opts = x509.VerifyOptions{
+		DNSName:       dnsname,
+		Roots:         r.Pool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	var _, err = cs.PeerCertificates[0].Verify(opts)
+	return err
+}
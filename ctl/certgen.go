@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/cert"
+)
+
+// CertgenCmd is "certgen" command settings.
+type CertgenCmd struct {
+	OutDir string        `short:"o" long:"out" default:"cert" description:"Directory to put generated files."`
+	CAName string        `long:"caname" default:"dfs local CA" description:"Common name of CA certificate."`
+	Certs  []string      `short:"c" long:"cert" description:"Certificate to generate in format 'name' or 'name:host1,host2', name is also used as host. Localhost addresses are always added to hosts."`
+	TTL    time.Duration `long:"ttl" default:"8760h" description:"Validity duration of generated certificates."`
+}
+
+func init() {
+	parser.AddCommand("certgen",
+		"Generate self-signed CA and certificates.",
+		"Generates self-signed CA and certificates signed by it for front and nodes of local compositions. Existing CA in output directory is reused. Files are written as 'ca.crt', 'ca.key', and 'name.crt', 'name.key' for each certificate.",
+		&CertgenCmd{})
+}
+
+// Execute is flags.Commander interface implementation.
+func (c *CertgenCmd) Execute(args []string) (err error) {
+	if err = os.MkdirAll(c.OutDir, 0755); err != nil {
+		return
+	}
+
+	// load or generate CA
+	var cacrt, cakey []byte
+	var crtpath = filepath.Join(c.OutDir, "ca.crt")
+	var keypath = filepath.Join(c.OutDir, "ca.key")
+	if cacrt, err = os.ReadFile(crtpath); err == nil {
+		if cakey, err = os.ReadFile(keypath); err != nil {
+			return
+		}
+		fmt.Printf("uses existing CA %s\n", crtpath)
+	} else if errors.Is(err, fs.ErrNotExist) {
+		if cacrt, cakey, err = cert.GenerateCA(c.CAName, c.TTL); err != nil {
+			return
+		}
+		if err = os.WriteFile(crtpath, cacrt, 0644); err != nil {
+			return
+		}
+		if err = os.WriteFile(keypath, cakey, 0600); err != nil {
+			return
+		}
+		fmt.Printf("generated CA %s\n", crtpath)
+	} else {
+		return
+	}
+
+	for _, s := range c.Certs {
+		var name, list, _ = strings.Cut(s, ":")
+		var hosts = []string{name, "localhost", "127.0.0.1", "::1"}
+		if list != "" {
+			hosts = append(hosts, strings.Split(list, ",")...)
+		}
+		var crt, key []byte
+		if crt, key, err = cert.GenerateCert(cacrt, cakey, name, hosts, c.TTL); err != nil {
+			return
+		}
+		if err = os.WriteFile(filepath.Join(c.OutDir, name+".crt"), crt, 0644); err != nil {
+			return
+		}
+		if err = os.WriteFile(filepath.Join(c.OutDir, name+".key"), key, 0600); err != nil {
+			return
+		}
+		fmt.Printf("generated certificate %s for %s\n", name, strings.Join(hosts, ", "))
+	}
+	return
+}
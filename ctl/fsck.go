@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrFsckProblems is "consistency problems are found" error message.
+var ErrFsckProblems = errors.New("consistency problems are found")
+
+// FsckCmd is "fsck" command settings.
+type FsckCmd struct {
+	Front   string        `env:"FRONTURL" long:"front" default:"http://localhost:8008" description:"Base URL of front REST API."`
+	APIKey  string        `env:"APIKEY" long:"key" description:"API key of admin, if authentication is enabled at front."`
+	Repair  bool          `long:"repair" description:"Repair found problems where it's possible."`
+	Verify  bool          `long:"verify" description:"Read content of files and compare it with checksums."`
+	Timeout time.Duration `long:"timeout" default:"5m" description:"Timeout of the check."`
+}
+
+// fsckProblem is problem of file found by consistency check.
+type fsckProblem struct {
+	FileID int64  `json:"file_id"`
+	Bucket string `json:"bucket"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	What   string `json:"what"`
+	Chunk  *struct {
+		NodeID int64 `json:"node_id"`
+		From   int64 `json:"from"`
+		To     int64 `json:"to"`
+	} `json:"chunk"`
+}
+
+// fsckNode is statistics counters of node compared with files metadata
+// and with content listed by node.
+type fsckNode struct {
+	Idx          int    `json:"idx"`
+	Addr         string `json:"addr"`
+	Size         int64  `json:"size"`
+	Chunks       int    `json:"chunks"`
+	ExpSize      int64  `json:"exp_size"`
+	ExpChunks    int    `json:"exp_chunks"`
+	StoredSize   int64  `json:"stored_size"`
+	StoredChunks int    `json:"stored_chunks"`
+	Listed       bool   `json:"listed"`
+	Mismatch     bool   `json:"mismatch"`
+	Repaired     bool   `json:"repaired"`
+}
+
+// fsckReport is result of consistency check returned by front.
+type fsckReport struct {
+	Files    int           `json:"files"`
+	Chunks   int           `json:"chunks"`
+	Repaired int           `json:"repaired"`
+	Problems []fsckProblem `json:"problems"`
+	Nodes    []fsckNode    `json:"nodes"`
+}
+
+func init() {
+	parser.AddCommand("fsck",
+		"Check up consistency of files metadata and nodes.",
+		"Front walks through all files and checks up their chunks at nodes, chunks bounds, checksums of content, and statistics counters of nodes. In repair mode counters are recounted. Lost chunks can not be repaired, storage keeps single copy of each chunk.",
+		&FsckCmd{})
+}
+
+// Execute is flags.Commander interface implementation.
+func (c *FsckCmd) Execute(args []string) (err error) {
+	var rep fsckReport
+	if err = c.call(&rep); err != nil {
+		return
+	}
+
+	var unrepaired int
+	for _, p := range rep.Problems {
+		var where string
+		if p.Chunk != nil {
+			where = fmt.Sprintf(" chunk %d-%d at node %d", p.Chunk.From, p.Chunk.To, p.Chunk.NodeID)
+		}
+		unrepaired++
+		fmt.Printf("file %d %s/%s%s: %s, %s\n", p.FileID, p.Bucket, p.Name, where, p.Kind, p.What)
+	}
+	for _, n := range rep.Nodes {
+		if !n.Mismatch {
+			continue
+		}
+		var state string
+		if n.Repaired {
+			state = ", repaired"
+		} else {
+			unrepaired++
+		}
+		var stored string
+		if n.Listed {
+			stored = fmt.Sprintf(", stored %d chunks %d bytes", n.StoredChunks, n.StoredSize)
+		}
+		fmt.Printf("node %d %s: counters %d chunks %d bytes, by metadata %d chunks %d bytes%s%s\n",
+			n.Idx, n.Addr, n.Chunks, n.Size, n.ExpChunks, n.ExpSize, stored, state)
+	}
+	fmt.Printf("files %d, chunks %d, problems %d, repaired %d\n",
+		rep.Files, rep.Chunks, len(rep.Problems), rep.Repaired)
+	if unrepaired > 0 {
+		err = ErrFsckProblems
+	}
+	return
+}
+
+func (c *FsckCmd) call(rep *fsckReport) (err error) {
+	var ctx, cancel = context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	var query = url.Values{}
+	query.Set("repair", strconv.FormatBool(c.Repair))
+	query.Set("verify", strconv.FormatBool(c.Verify))
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, c.Front+"/api/fsck?"+query.Encode(), nil); err != nil {
+		return
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var resp *http.Response
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	var body []byte
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("front returns status %d: %s", resp.StatusCode, body)
+		return
+	}
+	err = json.Unmarshal(body, rep)
+	return
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/cert"
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// CfgNodeTLS is TLS settings for gRPC connections to nodes.
+type CfgNodeTLS struct {
+	UseTLS   bool   `env:"NODETLS" long:"nodetls" description:"Use TLS for gRPC connections to nodes."`
+	CertFile string `env:"NODETLSCERT" long:"nodecert" description:"PEM file with client certificate presented to nodes for mutual TLS."`
+	KeyFile  string `env:"NODETLSKEY" long:"nodekey" description:"PEM file with private key of client certificate."`
+	CAFile   string `env:"NODETLSCA" long:"nodeca" description:"PEM file with CA certificates to verify nodes. System pool is used if it's not given."`
+}
+
+// Credentials returns transport credentials for gRPC connections to nodes.
+func (c *CfgNodeTLS) Credentials() (credentials.TransportCredentials, error) {
+	if !c.UseTLS {
+		return insecure.NewCredentials(), nil
+	}
+	var certs, err = cert.NewReloader(c.CertFile, c.KeyFile, c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(certs.ClientConfig()), nil
+}
+
+// RotateCmd is "rotate" command settings.
+type RotateCmd struct {
+	CfgNodeTLS `group:"Nodes TLS"`
+	NodeList   []string      `env:"NODELIST" env-delim:";" short:"n" long:"node" required:"true" description:"List of nodes to rotate encryption keys on."`
+	Timeout    time.Duration `long:"timeout" default:"5s" description:"Timeout to connect and call each node."`
+}
+
+func init() {
+	parser.AddCommand("rotate",
+		"Rotate encryption keys on nodes.",
+		"Nodes reload their encryption keys and start background re-encryption of chunks sealed by not active keys.",
+		&RotateCmd{})
+}
+
+// Execute is flags.Commander interface implementation.
+func (c *RotateCmd) Execute(args []string) (err error) {
+	var creds credentials.TransportCredentials
+	if creds, err = c.Credentials(); err != nil {
+		return
+	}
+	for _, addr := range c.NodeList {
+		if err1 := c.rotate(addr, creds); err1 != nil {
+			fmt.Printf("node %s: %v\n", addr, err1)
+			err = err1 // save error for future break
+			continue
+		}
+		fmt.Printf("node %s: rotation started\n", addr)
+	}
+	return
+}
+
+func (c *RotateCmd) rotate(addr string, creds credentials.TransportCredentials) (err error) {
+	var ctx, cancel = context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	var conn *grpc.ClientConn
+	if conn, err = grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+	); err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, err = pb.NewDataGuideClient(conn).Rotate(ctx, &emptypb.Empty{})
+	return
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Authentication methods.
+const (
+	AuthAnonymous = "anonymous"
+	AuthAPIKey    = "apikey"
+	AuthJWT       = "jwt"
+)
+
+// Role is access level of principal. Each role includes all lower roles.
+type Role int
+
+// Roles of principals.
+const (
+	RoleNone Role = iota
+	RoleReader
+	RoleWriter
+	RoleAdmin
+)
+
+var rolenames = [...]string{"none", "reader", "writer", "admin"}
+
+// String is fmt.Stringer interface implementation.
+func (role Role) String() string {
+	if role >= RoleNone && role <= RoleAdmin {
+		return rolenames[role]
+	}
+	return rolenames[RoleNone]
+}
+
+// MarshalText is encoding.TextMarshaler interface implementation.
+func (role Role) MarshalText() ([]byte, error) {
+	return []byte(role.String()), nil
+}
+
+// Principal is authenticated user of API.
+type Principal struct {
+	Name   string   `json:"name" yaml:"name" xml:"name"`
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty" xml:"groups>group,omitempty"`
+	Method string   `json:"method" yaml:"method" xml:"method"`
+	Role   Role     `json:"role" yaml:"role" xml:"role"`
+	Grant  *Grant   `json:"-" yaml:"-" xml:"-"` // constraints of presigned URL
+}
+
+// matches returns true if principal is pointed in given list of users and groups.
+func (p *Principal) matches(list []string) bool {
+	for _, s := range list {
+		if group, ok := strings.CutPrefix(s, "group:"); ok {
+			for _, g := range p.Groups {
+				if g == group {
+					return true
+				}
+			}
+		} else if s == p.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleOf returns highest role granted to principal by configuration.
+func (p *Principal) RoleOf() Role {
+	switch {
+	case p.matches(cfg.Admins):
+		return RoleAdmin
+	case p.matches(cfg.Writers):
+		return RoleWriter
+	case p.matches(cfg.Readers):
+		return RoleReader
+	default:
+		return RoleNone
+	}
+}
+
+// APIKey is record of API keys file.
+type APIKey struct {
+	Key    string   `json:"key" yaml:"key"`
+	Name   string   `json:"name" yaml:"name"`
+	Groups []string `json:"groups" yaml:"groups"`
+}
+
+// Authentication errors
+var (
+	ErrAuthNone   = errors.New("no credentials are given")
+	ErrAuthBadKey = errors.New("API key is not valid")
+	ErrAuthNoSub  = errors.New("JWT has no subject")
+	ErrAuthNoJWT  = errors.New("JWT authentication is not configured")
+	ErrAuthJWTAlg = errors.New("JWT signing method has no configured key")
+	ErrAuthEmpty  = errors.New("JWT secret is empty")
+	ErrForbidden  = errors.New("access denied for this role")
+)
+
+// Authenticator verifies credentials of requests.
+type Authenticator struct {
+	keys  map[[32]byte]*Principal // API keys by their SHA-256 hashes
+	hskey []byte                  // HS256 secret
+	rskey *rsa.PublicKey          // RS256 public key
+	pskey []byte                  // secret to sign URLs
+	mux   sync.RWMutex
+}
+
+// Authenticator is singleton.
+var auth Authenticator
+
+// Load reads API keys and JWT keys pointed by configuration.
+// Previous content is replaced only if all files are loaded successfully.
+func (a *Authenticator) Load() (err error) {
+	var keys map[[32]byte]*Principal
+	var hskey []byte
+	var rskey *rsa.PublicKey
+	var pskey []byte
+
+	if cfg.APIKeysFile != "" {
+		var list []APIKey
+		if err = ReadYaml(cfg.APIKeysFile, &list); err != nil {
+			return
+		}
+		keys = make(map[[32]byte]*Principal, len(list))
+		for _, rec := range list {
+			if rec.Key == "" {
+				continue
+			}
+			var p = &Principal{
+				Name:   rec.Name,
+				Groups: rec.Groups,
+				Method: AuthAPIKey,
+			}
+			p.Role = p.RoleOf()
+			keys[sha256.Sum256([]byte(rec.Key))] = p
+		}
+	}
+	if cfg.JWTSecretFile != "" {
+		var body []byte
+		if body, err = os.ReadFile(CfgPath(cfg.JWTSecretFile)); err != nil {
+			return
+		}
+		hskey = []byte(strings.TrimSpace(string(body)))
+		if len(hskey) == 0 {
+			err = ErrAuthEmpty
+			return
+		}
+	}
+	if cfg.JWTPublicKeyFile != "" {
+		var body []byte
+		if body, err = os.ReadFile(CfgPath(cfg.JWTPublicKeyFile)); err != nil {
+			return
+		}
+		if rskey, err = jwt.ParseRSAPublicKeyFromPEM(body); err != nil {
+			return
+		}
+	}
+	if cfg.PresignSecretFile != "" {
+		var body []byte
+		if body, err = os.ReadFile(CfgPath(cfg.PresignSecretFile)); err != nil {
+			return
+		}
+		pskey = []byte(strings.TrimSpace(string(body)))
+	} else if pskey = a.pskey; pskey == nil {
+		// signed URLs will be valid only until service restart
+		pskey = make([]byte, 32)
+		if _, err = rand.Read(pskey); err != nil {
+			return
+		}
+	}
+
+	a.mux.Lock()
+	a.keys, a.hskey, a.rskey, a.pskey = keys, hskey, rskey, pskey
+	a.mux.Unlock()
+	return
+}
+
+// Enabled returns true if any credentials source is configured.
+func (a *Authenticator) Enabled() bool {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	return a.keys != nil || a.hskey != nil || a.rskey != nil
+}
+
+// Authenticate returns principal for request credentials. API key is expected
+// at "X-API-Key" header, JWT is expected at "Authorization: Bearer" header.
+// Presigned URL is accepted without other credentials.
+// Returns anonymous principal with admin role if authentication is disabled.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.URL.Query().Has("sig") {
+		return a.CheckPresigned(r)
+	}
+	if !a.Enabled() {
+		return &Principal{Name: AuthAnonymous, Method: AuthAnonymous, Role: RoleAdmin}, nil
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.CheckAPIKey(key)
+	}
+	if val := r.Header.Get("Authorization"); len(val) > 7 && strings.EqualFold(val[:7], "Bearer ") {
+		return a.CheckJWT(strings.TrimSpace(val[7:]))
+	}
+	return nil, ErrAuthNone
+}
+
+// CheckAPIKey returns principal for given API key.
+func (a *Authenticator) CheckAPIKey(key string) (*Principal, error) {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	if p, ok := a.keys[sha256.Sum256([]byte(key))]; ok {
+		return p, nil
+	}
+	return nil, ErrAuthBadKey
+}
+
+// Claims is expected JWT claims set.
+type Claims struct {
+	jwt.RegisteredClaims
+	Groups []string `json:"groups,omitempty"`
+}
+
+// CheckJWT verifies given JWT and returns principal for its subject.
+// Any JWT is refused if no JWT key is configured.
+func (a *Authenticator) CheckJWT(token string) (*Principal, error) {
+	a.mux.RLock()
+	var hskey, rskey = a.hskey, a.rskey
+	a.mux.RUnlock()
+
+	var methods []string
+	if hskey != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if rskey != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		// empty list of valid methods turns off methods check
+		return nil, ErrAuthNoJWT
+	}
+	var opts = []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodRS256.Alg():
+			if rskey != nil {
+				return rskey, nil
+			}
+		case jwt.SigningMethodHS256.Alg():
+			if hskey != nil {
+				return hskey, nil
+			}
+		}
+		return nil, ErrAuthJWTAlg
+	}, opts...); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, ErrAuthNoSub
+	}
+	var p = &Principal{
+		Name:   claims.Subject,
+		Groups: claims.Groups,
+		Method: AuthJWT,
+	}
+	p.Role = p.RoleOf()
+	return p, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns copy of context with given principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// GetPrincipal returns principal attached to request context,
+// or nil if request was not authenticated.
+func GetPrincipal(r *http.Request) *Principal {
+	var p, _ = r.Context().Value(principalKey{}).(*Principal)
+	return p
+}
+
+// AuthMiddleware authenticates API calls and attaches principal to request context.
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p, err = auth.Authenticate(r)
+		if err != nil {
+			var code = AECauthbadjwt
+			if errors.Is(err, ErrAuthNone) {
+				code = AECauthnone
+			} else if errors.Is(err, ErrAuthBadKey) {
+				code = AECauthbadkey
+			} else if errors.Is(err, ErrSigBad) || errors.Is(err, ErrSigExpired) ||
+				errors.Is(err, ErrSigMethod) || errors.Is(err, ErrSigArgs) {
+				code = AECauthbadsig
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dfs"`)
+			WriteError(w, r, http.StatusUnauthorized, err, code)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// Allow wraps handler to permit access only for principals with given role or higher.
+func Allow(role Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var p = GetPrincipal(r)
+		if p == nil || p.Role < role ||
+			(p.Grant != nil && p.Grant.Path != r.URL.Path) {
+			WriteError(w, r, http.StatusForbidden, ErrForbidden, AECforbidden)
+			return
+		}
+		next(w, r)
+	}
+}
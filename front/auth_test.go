@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCheckJWT(t *testing.T) {
+	var saved = cfg.CfgAuth
+	t.Cleanup(func() { cfg.CfgAuth = saved })
+	cfg.CfgRoles = CfgRoles{
+		Readers: []string{"carol"},
+		Writers: []string{"group:dev"},
+		Admins:  []string{"root"},
+	}
+	cfg.JWTIssuer, cfg.JWTAudience = "", ""
+
+	var hskey = []byte("jwt secret")
+	var rskey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var otherkey *rsa.PrivateKey
+	if otherkey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	var pubder, _ = x509.MarshalPKIXPublicKey(&rskey.PublicKey)
+	var pubpem = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubder})
+
+	var claims = func(sub string, exp time.Duration, groups ...string) Claims {
+		var c = Claims{Groups: groups}
+		c.Subject = sub
+		if exp != 0 {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(exp))
+		}
+		return c
+	}
+	var sign = func(method jwt.SigningMethod, key any, c Claims) string {
+		var s, err = jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	var both = &Authenticator{hskey: hskey, rskey: &rskey.PublicKey}
+	var hsonly = &Authenticator{hskey: hskey}
+	var rsonly = &Authenticator{rskey: &rskey.PublicKey}
+	var tests = []struct {
+		what  string
+		a     *Authenticator
+		token string
+		role  Role
+		err   error
+	}{
+		{"HS256", both, sign(jwt.SigningMethodHS256, hskey, claims("alice", time.Hour, "dev")), RoleWriter, nil},
+		{"RS256", both, sign(jwt.SigningMethodRS256, rskey, claims("root", time.Hour)), RoleAdmin, nil},
+		{"reader", hsonly, sign(jwt.SigningMethodHS256, hskey, claims("carol", time.Hour)), RoleReader, nil},
+		{"no role", hsonly, sign(jwt.SigningMethodHS256, hskey, claims("dave", time.Hour)), RoleNone, nil},
+		{"bad HS256 signature", both, sign(jwt.SigningMethodHS256, []byte("other secret"), claims("alice", time.Hour, "dev")), 0, jwt.ErrTokenSignatureInvalid},
+		{"bad RS256 signature", both, sign(jwt.SigningMethodRS256, otherkey, claims("root", time.Hour)), 0, jwt.ErrTokenSignatureInvalid},
+		{"expired", both, sign(jwt.SigningMethodHS256, hskey, claims("alice", -time.Minute, "dev")), 0, jwt.ErrTokenExpired},
+		{"no expiry", both, sign(jwt.SigningMethodHS256, hskey, claims("alice", 0, "dev")), 0, jwt.ErrTokenRequiredClaimMissing},
+		{"no subject", both, sign(jwt.SigningMethodHS256, hskey, claims("", time.Hour)), 0, ErrAuthNoSub},
+		{"method without key", hsonly, sign(jwt.SigningMethodRS256, rskey, claims("root", time.Hour)), 0, jwt.ErrTokenSignatureInvalid},
+		{"public key as HMAC secret", rsonly, sign(jwt.SigningMethodHS256, pubpem, claims("root", time.Hour)), 0, jwt.ErrTokenSignatureInvalid},
+		{"unsigned", both, sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims("root", time.Hour)), 0, jwt.ErrTokenSignatureInvalid},
+		{"not configured", &Authenticator{}, sign(jwt.SigningMethodHS256, hskey, claims("alice", time.Hour, "dev")), 0, ErrAuthNoJWT},
+		{"garbage", both, "not.a.token", 0, jwt.ErrTokenMalformed},
+	}
+	for _, test := range tests {
+		var p, err = test.a.CheckJWT(test.token)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.what, test.err, err)
+			continue
+		}
+		if err == nil && p.Role != test.role {
+			t.Errorf("%s: got role %s, expected %s", test.what, p.Role, test.role)
+		}
+	}
+
+	// issuer and audience are checked if they are given
+	cfg.JWTIssuer, cfg.JWTAudience = "dfs", "front"
+	var c = claims("alice", time.Hour, "dev")
+	c.Issuer, c.Audience = "other", jwt.ClaimStrings{"front"}
+	if _, err = both.CheckJWT(sign(jwt.SigningMethodHS256, hskey, c)); !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+		t.Errorf("other issuer: expected error %v, got %v", jwt.ErrTokenInvalidIssuer, err)
+	}
+	c.Issuer, c.Audience = "dfs", jwt.ClaimStrings{"other"}
+	if _, err = both.CheckJWT(sign(jwt.SigningMethodHS256, hskey, c)); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		t.Errorf("other audience: expected error %v, got %v", jwt.ErrTokenInvalidAudience, err)
+	}
+	c.Audience = jwt.ClaimStrings{"front"}
+	if _, err = both.CheckJWT(sign(jwt.SigningMethodHS256, hskey, c)); err != nil {
+		t.Errorf("expected issuer and audience: %v", err)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	var tests = []struct {
+		what   string
+		role   Role
+		p      *Principal
+		path   string
+		status int
+	}{
+		{"no principal", RoleReader, nil, "/api/list", http.StatusForbidden},
+		{"no role", RoleReader, &Principal{Name: "dave"}, "/api/list", http.StatusForbidden},
+		{"reader", RoleReader, &Principal{Name: "carol", Role: RoleReader}, "/api/list", http.StatusOK},
+		{"reader uploads", RoleWriter, &Principal{Name: "carol", Role: RoleReader}, "/api/upload", http.StatusForbidden},
+		{"writer", RoleWriter, &Principal{Name: "alice", Role: RoleWriter}, "/api/upload", http.StatusOK},
+		{"writer on admin call", RoleAdmin, &Principal{Name: "alice", Role: RoleWriter}, "/api/node/add", http.StatusForbidden},
+		{"admin", RoleAdmin, &Principal{Name: "root", Role: RoleAdmin}, "/api/node/add", http.StatusOK},
+		{"grant path", RoleReader, &Principal{Role: RoleReader, Grant: &Grant{Path: "/api/download"}}, "/api/download", http.StatusOK},
+		{"grant on other path", RoleReader, &Principal{Role: RoleReader, Grant: &Grant{Path: "/api/download"}}, "/api/fileinfo", http.StatusForbidden},
+		{"upload grant on other path", RoleWriter, &Principal{Role: RoleWriter, Grant: &Grant{Path: "/api/upload"}}, "/api/remove", http.StatusForbidden},
+	}
+	var ok = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	for _, test := range tests {
+		var r = httptest.NewRequest("POST", test.path, nil)
+		if test.p != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), test.p))
+		}
+		var w = httptest.NewRecorder()
+		Allow(test.role, ok)(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s: status %d, expected %d", test.what, w.Code, test.status)
+		}
+	}
+}
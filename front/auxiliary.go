@@ -3,12 +3,11 @@ package main
 import (
 	"errors"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
-
-	"google.golang.org/grpc/grpclog"
 )
 
 var (
@@ -71,6 +70,16 @@ var ConfigPath string
 // ErrNoCongig is "no configuration path was found" error message.
 var ErrNoCongig = errors.New("no configuration path was found")
 
+// CfgPath returns given file path if it's absolute,
+// or path relative to configuration path otherwise.
+func CfgPath(fpath string) string {
+	fpath = filepath.ToSlash(EnvFmt(fpath))
+	if path.IsAbs(fpath) || filepath.IsAbs(fpath) {
+		return fpath
+	}
+	return path.Join(ConfigPath, fpath)
+}
+
 // DetectConfigPath finds configuration path with existing configuration file at least.
 func DetectConfigPath() (retpath string, err error) {
 	var ok bool
@@ -88,7 +97,7 @@ func DetectConfigPath() (retpath string, err error) {
 		if retpath, ok = CheckPath(path.Join(exepath, fpath), cfgfile); ok {
 			return
 		}
-		grpclog.Warningf("no access to pointed configuration path '%s'\n", fpath)
+		slog.Warn("no access to pointed configuration path", "path", fpath)
 	}
 
 	// try to get from config subdirectory on executable path
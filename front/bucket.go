@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+)
+
+// DefaultBucket is name of bucket for files uploaded without pointed bucket.
+// It always exists and can not be deleted.
+const DefaultBucket = "default"
+
+// Buckets errors
+var (
+	ErrBucketName     = errors.New("bucket name must have 1-63 lowercase letters, digits, '-' or '_' symbols")
+	ErrBucketAbsent   = errors.New("bucket with given name is not found")
+	ErrBucketHas      = errors.New("bucket with given name already present")
+	ErrBucketNotEmpty = errors.New("bucket is not empty")
+	ErrBucketDefault  = errors.New("default bucket can not be deleted")
+	ErrBucketAccess   = errors.New("access to bucket is denied")
+	ErrQuotaBytes     = errors.New("bucket bytes quota is exceeded")
+	ErrQuotaObjects   = errors.New("bucket objects quota is exceeded")
+)
+
+var bktre = regexp.MustCompile(`^[a-z0-9_\-]{1,63}$`)
+
+// Bucket is isolated namespace of files with its own quotas.
+type Bucket struct {
+	XMLName xml.Name `json:"-" yaml:"-" xml:"bucket"`
+
+	Name string `json:"name" yaml:"name" xml:"name"`
+	// user or group with "group:" prefix who uses bucket, bucket without owner is used by everyone
+	Owner      string `json:"owner" yaml:"owner" xml:"owner"`
+	Created    unix_t `json:"created" yaml:"created" xml:"created"`
+	MaxBytes   int64  `json:"max_bytes" yaml:"max_bytes" xml:"max_bytes"`       // zero for unlimited
+	MaxObjects int64  `json:"max_objects" yaml:"max_objects" xml:"max_objects"` // zero for unlimited
+	// usage statistics
+	Bytes   int64 `json:"bytes" yaml:"bytes" xml:"bytes"`
+	Objects int64 `json:"objects" yaml:"objects" xml:"objects"`
+	// place reserved for files being uploaded
+	pendbytes, pendobjects int64
+}
+
+// BucketName returns given bucket name, or default bucket name if it's empty.
+func BucketName(name string) string {
+	if name == "" {
+		return DefaultBucket
+	}
+	return name
+}
+
+// CanUse returns true if given principal can upload files into bucket,
+// list its files and sign URLs for it, that is if principal is owner
+// of bucket, or member of owner group, or admin.
+func (bkt *Bucket) CanUse(p *Principal) bool {
+	return p.Role == RoleAdmin || bkt.Owner == "" || p.matches([]string{bkt.Owner})
+}
+
+// AddBucket creates new bucket. Returns copy of added bucket.
+func (s *Storage) AddBucket(bkt *Bucket) (cp Bucket, err error) {
+	s.bktmux.Lock()
+	defer s.bktmux.Unlock()
+	if _, ok := s.Buckets[bkt.Name]; ok {
+		err = ErrBucketHas
+		return
+	}
+	s.Buckets[bkt.Name] = bkt
+	cp = *bkt
+	return
+}
+
+// DelBucket deletes empty bucket with given name.
+func (s *Storage) DelBucket(name string) (bkt *Bucket, err error) {
+	if name == DefaultBucket {
+		return nil, ErrBucketDefault
+	}
+	s.bktmux.Lock()
+	defer s.bktmux.Unlock()
+	var ok bool
+	if bkt, ok = s.Buckets[name]; !ok {
+		return nil, ErrBucketAbsent
+	}
+	if bkt.Objects > 0 || bkt.pendobjects > 0 {
+		return nil, ErrBucketNotEmpty
+	}
+	delete(s.Buckets, name)
+	return
+}
+
+// GetBuckets returns copies of all buckets sorted by names.
+func (s *Storage) GetBuckets() (list []Bucket) {
+	s.bktmux.RLock()
+	list = make([]Bucket, 0, len(s.Buckets))
+	for _, bkt := range s.Buckets {
+		list = append(list, *bkt)
+	}
+	s.bktmux.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+// GetBucket returns copy of bucket with given name,
+// and false if there is no such bucket.
+func (s *Storage) GetBucket(name string) (bkt Bucket, ok bool) {
+	s.bktmux.RLock()
+	defer s.bktmux.RUnlock()
+	var ptr *Bucket
+	if ptr, ok = s.Buckets[name]; ok {
+		bkt = *ptr
+	}
+	return
+}
+
+// HasBucket returns true if bucket with given name is present.
+func (s *Storage) HasBucket(name string) bool {
+	s.bktmux.RLock()
+	defer s.bktmux.RUnlock()
+	var _, ok = s.Buckets[name]
+	return ok
+}
+
+// Reserve checks up quotas of bucket with files being uploaded,
+// and reserves place for new file with given size. File is accounted
+// in bucket usage when it's added, and reservation must be canceled then.
+func (s *Storage) Reserve(name string, size int64) error {
+	s.bktmux.Lock()
+	defer s.bktmux.Unlock()
+	var bkt, ok = s.Buckets[name]
+	if !ok {
+		return ErrBucketAbsent
+	}
+	if bkt.MaxBytes > 0 && bkt.Bytes+bkt.pendbytes+size > bkt.MaxBytes {
+		return ErrQuotaBytes
+	}
+	if bkt.MaxObjects > 0 && bkt.Objects+bkt.pendobjects+1 > bkt.MaxObjects {
+		return ErrQuotaObjects
+	}
+	bkt.pendbytes += size
+	bkt.pendobjects++
+	return nil
+}
+
+// Unreserve cancels reservation of place for file with given size.
+func (s *Storage) Unreserve(name string, size int64) {
+	s.bktmux.Lock()
+	defer s.bktmux.Unlock()
+	if bkt, ok := s.Buckets[name]; ok {
+		bkt.pendbytes -= size
+		bkt.pendobjects--
+	}
+}
+
+// Account adds file with given size to bucket usage.
+func (s *Storage) Account(name string, size int64) {
+	s.bktmux.Lock()
+	defer s.bktmux.Unlock()
+	if bkt, ok := s.Buckets[name]; ok {
+		bkt.Bytes += size
+		bkt.Objects++
+	}
+}
+
+// Release removes file with given size from bucket usage.
+func (s *Storage) Release(name string, size int64) {
+	s.bktmux.Lock()
+	defer s.bktmux.Unlock()
+	if bkt, ok := s.Buckets[name]; ok {
+		bkt.Bytes -= size
+		bkt.Objects--
+	}
+}
+
+// ListFiles returns all files of bucket with given name, sorted by IDs.
+func (s *Storage) ListFiles(name string) (list []*FileInfo) {
+	s.FIMap.Range(func(key interface{}, value interface{}) bool {
+		var fi = value.(*FileInfo)
+		if fi.Bucket == name {
+			list = append(list, fi)
+		}
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].FileID < list[j].FileID
+	})
+	return
+}
+
+// bucketsizeAPI returns usage statistics of all buckets.
+func (f *Front) bucketsizeAPI(w http.ResponseWriter, r *http.Request) {
+	type usage struct {
+		Name    string `json:"name" yaml:"name" xml:"name"`
+		Bytes   int64  `json:"bytes" yaml:"bytes" xml:"bytes"`
+		Objects int64  `json:"objects" yaml:"objects" xml:"objects"`
+	}
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		List []usage `json:"list" yaml:"list" xml:"list>bucket"`
+	}
+
+	var buckets = f.Storage.GetBuckets()
+	ret.List = make([]usage, len(buckets))
+	for i, bkt := range buckets {
+		ret.List[i] = usage{
+			Name:    bkt.Name,
+			Bytes:   bkt.Bytes,
+			Objects: bkt.Objects,
+		}
+	}
+
+	WriteOK(w, r, &ret)
+}
+
+// bucketcreateAPI creates new bucket with given quotas.
+func (f *Front) bucketcreateAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Name       string `json:"name" yaml:"name" xml:"name"`
+		Owner      string `json:"owner,omitempty" yaml:"owner,omitempty" xml:"owner,omitempty"` // creator is owner if it's empty
+		MaxBytes   int64  `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty" xml:"max_bytes,omitempty"`
+		MaxObjects int64  `json:"max_objects,omitempty" yaml:"max_objects,omitempty" xml:"max_objects,omitempty"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	if !bktre.MatchString(arg.Name) {
+		WriteError400(w, r, ErrBucketName, AECbucketcreatename)
+		return
+	}
+
+	if arg.Owner == "" {
+		arg.Owner = GetPrincipal(r).Name
+	}
+
+	var bkt = &Bucket{
+		Name:       arg.Name,
+		Owner:      arg.Owner,
+		Created:    UnixJSNow(),
+		MaxBytes:   arg.MaxBytes,
+		MaxObjects: arg.MaxObjects,
+	}
+	var res any
+	if res, err = f.ApplyCmd(&Command{Op: OpAddBucket, Bucket: bkt}); err != nil {
+		if errors.Is(err, ErrBucketHas) {
+			WriteError(w, r, http.StatusConflict, err, AECbucketcreatehas)
+		} else {
+			WriteError(w, r, http.StatusServiceUnavailable, err, AECbucketcreateapply)
+		}
+		return
+	}
+	// added bucket is changed by quotas accounting, its copy is returned
+	var cp = res.(Bucket)
+
+	WriteOK(w, r, &cp)
+}
+
+// bucketlistAPI returns all buckets with their quotas and usage.
+func (f *Front) bucketlistAPI(w http.ResponseWriter, r *http.Request) {
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		List []Bucket `json:"list" yaml:"list" xml:"list>bucket"`
+	}
+
+	ret.List = f.Storage.GetBuckets()
+
+	WriteOK(w, r, &ret)
+}
+
+// bucketdeleteAPI deletes empty bucket by its owner or admin.
+func (f *Front) bucketdeleteAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Name string `json:"name" yaml:"name" xml:"name"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	if arg.Name == "" {
+		WriteError400(w, r, ErrNoData, AECbucketdeletenoarg)
+		return
+	}
+	if bkt, ok := f.Storage.GetBucket(arg.Name); !ok {
+		WriteError(w, r, http.StatusNotFound, ErrBucketAbsent, AECbucketdeleteabsent)
+		return
+	} else if !bkt.CanUse(GetPrincipal(r)) {
+		WriteError(w, r, http.StatusForbidden, ErrBucketAccess, AECbucketdeleteaccess)
+		return
+	}
+
+	var res any
+	if res, err = f.ApplyCmd(&Command{Op: OpDelBucket, Name: arg.Name}); err != nil {
+		if errors.Is(err, ErrBucketAbsent) {
+			WriteError(w, r, http.StatusNotFound, err, AECbucketdeleteabsent)
+		} else if errors.Is(err, ErrBucketNotEmpty) || errors.Is(err, ErrBucketDefault) {
+			WriteError(w, r, http.StatusConflict, err, AECbucketdeletehas)
+		} else {
+			WriteError(w, r, http.StatusServiceUnavailable, err, AECbucketdeleteapply)
+		}
+		return
+	}
+
+	WriteOK(w, r, res.(*Bucket))
+}
+
+// listAPI returns files of bucket accessible by principal,
+// bucket must be used by principal.
+func (f *Front) listAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Bucket string `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+	}
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		List []*FileInfo `json:"list" yaml:"list" xml:"list>fi"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	arg.Bucket = BucketName(arg.Bucket)
+	var p = GetPrincipal(r)
+	if bkt, ok := f.Storage.GetBucket(arg.Bucket); !ok {
+		WriteError(w, r, http.StatusNotFound, ErrBucketAbsent, AEClistabsent)
+		return
+	} else if !bkt.CanUse(p) {
+		WriteError(w, r, http.StatusForbidden, ErrBucketAccess, AEClistaccess)
+		return
+	}
+
+	ret.List = []*FileInfo{}
+	for _, fi := range f.Storage.ListFiles(arg.Bucket) {
+		if fi.CanRead(p) {
+			ret.List = append(ret.List, fi)
+		}
+	}
+
+	WriteOK(w, r, &ret)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBucketOwner(t *testing.T) {
+	StartExit(t)
+	var _, server = StartTestFront(t, 1)
+	StartTestAuth(t,
+		testUser{Name: "root", Role: RoleAdmin},
+		testUser{Name: "alice", Role: RoleWriter, Groups: []string{"team1"}},
+		testUser{Name: "bob", Role: RoleWriter, Groups: []string{"team2"}},
+	)
+	var api = func(path string) string {
+		return server.URL + "/api/" + path
+	}
+
+	var bkt Bucket
+	if status := CallAPI(t, api("bucket/create"), "root", `{"name":"team1","owner":"group:team1"}`, &bkt); status != http.StatusOK {
+		t.Fatalf("bucket create status %d", status)
+	}
+	if bkt.Owner != "group:team1" {
+		t.Errorf("bucket is created with owner %q", bkt.Owner)
+	}
+	if status := CallAPI(t, api("bucket/create"), "root", `{"name":"solo","owner":"bob"}`, nil); status != http.StatusOK {
+		t.Fatalf("bucket create status %d", status)
+	}
+
+	var tests = []struct {
+		what, user string
+		call       func(user string) int
+		status     int
+	}{
+		{"upload", "alice", func(user string) int {
+			var status, _ = UploadFile(t, api("upload?bucket=team1"), user, "a.txt", []byte("team1 data"))
+			return status
+		}, http.StatusOK},
+		{"upload", "bob", func(user string) int {
+			var status, _ = UploadFile(t, api("upload?bucket=team1"), user, "b.txt", []byte("team2 data"))
+			return status
+		}, http.StatusForbidden},
+		{"upload to default", "bob", func(user string) int {
+			var status, _ = UploadFile(t, api("upload"), user, "b.txt", []byte("team2 data"))
+			return status
+		}, http.StatusOK},
+		{"list", "alice", func(user string) int {
+			return CallAPI(t, api("list"), user, `{"bucket":"team1"}`, nil)
+		}, http.StatusOK},
+		{"list", "bob", func(user string) int {
+			return CallAPI(t, api("list"), user, `{"bucket":"team1"}`, nil)
+		}, http.StatusForbidden},
+		{"list", "root", func(user string) int {
+			return CallAPI(t, api("list"), user, `{"bucket":"team1"}`, nil)
+		}, http.StatusOK},
+		{"presign upload", "alice", func(user string) int {
+			return CallAPI(t, api("presign"), user, `{"method":"POST","bucket":"team1","ttl":60}`, nil)
+		}, http.StatusOK},
+		{"presign upload", "bob", func(user string) int {
+			return CallAPI(t, api("presign"), user, `{"method":"POST","bucket":"team1","ttl":60}`, nil)
+		}, http.StatusForbidden},
+		{"delete not empty", "bob", func(user string) int {
+			return CallAPI(t, api("bucket/delete"), user, `{"name":"team1"}`, nil)
+		}, http.StatusForbidden},
+		{"delete not empty", "alice", func(user string) int {
+			return CallAPI(t, api("bucket/delete"), user, `{"name":"team1"}`, nil)
+		}, http.StatusConflict},
+		{"delete", "alice", func(user string) int {
+			return CallAPI(t, api("bucket/delete"), user, `{"name":"solo"}`, nil)
+		}, http.StatusForbidden},
+		{"delete", "bob", func(user string) int {
+			return CallAPI(t, api("bucket/delete"), user, `{"name":"solo"}`, nil)
+		}, http.StatusOK},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d %s by %s", i, test.what, test.user), func(t *testing.T) {
+			if status := test.call(test.user); status != test.status {
+				t.Errorf("status %d, expected %d", status, test.status)
+			}
+		})
+	}
+}
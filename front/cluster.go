@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// MinProtocol is minimum version of DataGuide protocol of nodes
+// supported by front, maximum version is pb.Protocol.
+const MinProtocol = 1
+
+var (
+	// ErrProtocol is "incompatible protocol version of node" error message.
+	ErrProtocol = errors.New("incompatible protocol version of node")
+	// ErrNodeUnavailable is "node is unavailable" error message.
+	ErrNodeUnavailable = errors.New("node is unavailable")
+	// ErrNoNodes is "there is no available nodes" error message.
+	ErrNoNodes = errors.New("there is no available nodes")
+	// ErrNoTier is "there is no available nodes of storage class tier" error message.
+	ErrNoTier = errors.New("there is no available nodes of storage class tier")
+	// ErrStartupTimeout is "nodes are not connected during startup timeout" error message.
+	ErrStartupTimeout = errors.New("nodes are not connected during startup timeout")
+)
+
+// NodeStatus is state of node shown in cluster status.
+type NodeStatus struct {
+	XMLName xml.Name `json:"-" yaml:"-" xml:"node"`
+
+	Idx       int      `json:"idx" yaml:"idx" xml:"idx"`
+	Addr      string   `json:"addr" yaml:"addr" xml:"addr"`
+	ID        string   `json:"id" yaml:"id" xml:"id"`
+	Tier      string   `json:"tier,omitempty" yaml:"tier,omitempty" xml:"tier,omitempty"`
+	State     string   `json:"state" yaml:"state" xml:"state"`
+	Available bool     `json:"available" yaml:"available" xml:"available"` // connected and passed handshake
+	Departed  bool     `json:"departed" yaml:"departed" xml:"departed"`    // registered node stopped heartbeats
+	LastBeat  unix_t   `json:"last_beat" yaml:"last_beat" xml:"last_beat"` // zero if node is not registered
+	LastSeen  unix_t   `json:"last_seen" yaml:"last_seen" xml:"last_seen"` // zero if node was never seen
+	Size      int64    `json:"size" yaml:"size" xml:"size"`
+	Chunks    int      `json:"chunks" yaml:"chunks" xml:"chunks"`
+	Calls     int64    `json:"calls" yaml:"calls" xml:"calls"`
+	Errors    int64    `json:"errors" yaml:"errors" xml:"errors"`
+	ErrorRate float64  `json:"error_rate" yaml:"error_rate" xml:"error_rate"` // ratio of failed calls
+	Version   string   `json:"version" yaml:"version" xml:"version"`
+	BuildDate string   `json:"builddate" yaml:"builddate" xml:"builddate"`
+	Protocol  int32    `json:"protocol" yaml:"protocol" xml:"protocol"`
+	Features  []string `json:"features" yaml:"features" xml:"features>feature"`
+}
+
+// FrontsStatus is state of fronts cluster shown in cluster status.
+type FrontsStatus struct {
+	ID     string `json:"id" yaml:"id" xml:"id"`
+	State  string `json:"state" yaml:"state" xml:"state"`
+	Leader string `json:"leader" yaml:"leader" xml:"leader"`
+}
+
+// Status returns current state of node.
+func (node *NodeInfo) Status() (st NodeStatus) {
+	st.Addr = node.Addr
+	st.State = connectivity.Shutdown.String()
+	if node.conn != nil {
+		st.State = node.conn.GetState().String()
+	}
+	st.Available = node.Available()
+	st.Departed = node.departed.Load()
+	st.Size = node.SumSize
+	st.Chunks = node.NumChunks
+	st.Calls = node.calls.Load()
+	st.Errors = node.fails.Load()
+	if st.Calls > 0 {
+		st.ErrorRate = float64(st.Errors) / float64(st.Calls)
+	}
+	node.hbmux.RLock()
+	if info := node.info; info != nil {
+		st.ID, st.Tier, st.Version, st.BuildDate = info.Id, info.Tier, info.Version, info.Builddate
+		st.Protocol, st.Features = info.Protocol, info.Features
+	}
+	if !node.lastseen.IsZero() {
+		st.LastSeen = UnixJS(node.lastseen)
+	}
+	if !node.regbeat.IsZero() {
+		st.LastBeat = UnixJS(node.regbeat)
+	}
+	node.hbmux.RUnlock()
+	return
+}
+
+// Handshake receives identity, build and protocol version of node,
+// and checks up that protocol is supported by front.
+func (node *NodeInfo) Handshake() (info *pb.NodeInfo, err error) {
+	var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+	defer cancel()
+	if info, err = node.Client.Info(ctx, &emptypb.Empty{}); err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			err = fmt.Errorf("%w: node has no Info call, front supports %d-%d",
+				ErrProtocol, MinProtocol, pb.Protocol)
+		}
+		return
+	}
+	if info.Protocol < MinProtocol || info.Protocol > pb.Protocol {
+		err = fmt.Errorf("%w: node has %d, front supports %d-%d",
+			ErrProtocol, info.Protocol, MinProtocol, pb.Protocol)
+		return
+	}
+	node.hbmux.Lock()
+	node.info = info
+	node.hbmux.Unlock()
+	return
+}
+
+// Available reports whether node is connected, has passed handshake
+// and is not departed, so it can be used for reads and writes.
+func (node *NodeInfo) Available() bool {
+	return node.ready.Load() && !node.departed.Load()
+}
+
+// HasFeature returns true if node has passed handshake
+// and announced given optional feature.
+func (node *NodeInfo) HasFeature(feature string) bool {
+	node.hbmux.RLock()
+	defer node.hbmux.RUnlock()
+	return node.info != nil && node.info.HasFeature(feature)
+}
+
+// Tier returns storage tier label announced by node on handshake,
+// it's empty if node has no tier or handshake was not passed.
+func (node *NodeInfo) Tier() string {
+	node.hbmux.RLock()
+	defer node.hbmux.RUnlock()
+	return node.info.GetTier()
+}
+
+// Connect makes handshake with node on established connection,
+// and makes node available on success.
+func (node *NodeInfo) Connect() {
+	var info, err = node.Handshake()
+	if err != nil {
+		if exitctx.Err() == nil { // not canceled by exit
+			slog.Error("node is refused", "addr", node.Addr, "error", err)
+		}
+		return
+	}
+	node.ready.Store(true)
+	node.cononce.Do(func() { close(node.connected) })
+	slog.Info("node handshake complete", "addr", node.Addr, "id", info.Id,
+		"version", info.Version, "protocol", info.Protocol, "tier", info.Tier, "features", info.Features)
+}
+
+// Watch follows connectivity state of given connection until exit signal.
+// Node becomes unavailable when connection is lost, gRPC reconnects it
+// with exponential backoff, and node becomes available again after
+// new handshake, so restarted node is always checked up. Refused node
+// repeats handshake with heartbeat period.
+func (node *NodeInfo) Watch(conn *grpc.ClientConn) {
+	var state = conn.GetState()
+	for {
+		if state == connectivity.Ready {
+			if !node.ready.Load() {
+				node.Connect()
+			}
+		} else if node.ready.Swap(false) {
+			slog.Warn("node is unavailable", "addr", node.Addr, "state", state.String())
+		}
+		if state == connectivity.Idle {
+			conn.Connect() // lost connection stays idle until some call without it
+		}
+
+		var ctx, cancel = exitctx, context.CancelFunc(func() {})
+		if state == connectivity.Ready && !node.ready.Load() {
+			ctx, cancel = context.WithTimeout(exitctx, cfg.HeartbeatPeriod)
+		}
+		conn.WaitForStateChange(ctx, state)
+		cancel()
+		if exitctx.Err() != nil {
+			return
+		}
+		state = conn.GetState()
+	}
+}
+
+// Heartbeat checks health of node by given connection with
+// heartbeat period until exit signal.
+func (node *NodeInfo) Heartbeat(conn *grpc.ClientConn) {
+	var client = healthpb.NewHealthClient(conn)
+	var check = func() {
+		var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+		defer cancel()
+		var res, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			slog.Warn("node health check failed", "addr", node.Addr, "error", err)
+			return
+		}
+		if res.Status != healthpb.HealthCheckResponse_SERVING {
+			slog.Warn("node is not serving", "addr", node.Addr, "status", res.Status.String())
+			return
+		}
+		node.hbmux.Lock()
+		node.lastseen = time.Now()
+		node.hbmux.Unlock()
+	}
+
+	check()
+	var ticker = time.NewTicker(cfg.HeartbeatPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exitctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// clusterAPI returns state of all nodes, and storage totals.
+func (f *Front) clusterAPI(w http.ResponseWriter, r *http.Request) {
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		Nodes []NodeStatus `json:"nodes" yaml:"nodes" xml:"nodes>node"`
+		Total struct {
+			Nodes int   `json:"nodes" yaml:"nodes" xml:"nodes"`
+			Ready int   `json:"ready" yaml:"ready" xml:"ready"`
+			Size  int64 `json:"size" yaml:"size" xml:"size"`
+			Files int   `json:"files" yaml:"files" xml:"files"`
+		} `json:"total" yaml:"total" xml:"total"`
+		Degraded  bool          `json:"degraded" yaml:"degraded" xml:"degraded"`                         // some nodes are unavailable
+		Fronts    *FrontsStatus `json:"fronts,omitempty" yaml:"fronts,omitempty" xml:"fronts,omitempty"` // nil if fronts cluster is off
+		Version   string        `json:"version" yaml:"version" xml:"version"`
+		BuildDate string        `json:"builddate" yaml:"builddate" xml:"builddate"`
+	}
+
+	f.Storage.nodmux.RLock()
+	ret.Nodes = make([]NodeStatus, len(f.Storage.Nodes))
+	for i, node := range f.Storage.Nodes {
+		var st = node.Status()
+		st.Idx = i
+		ret.Nodes[i] = st
+		ret.Total.Size += st.Size
+		if st.Available {
+			ret.Total.Ready++
+		}
+	}
+	f.Storage.nodmux.RUnlock()
+	ret.Total.Nodes = len(ret.Nodes)
+	ret.Degraded = ret.Total.Ready < ret.Total.Nodes
+	if f.Replica != nil {
+		ret.Fronts = &FrontsStatus{
+			ID:     f.Replica.ID,
+			State:  f.Replica.Raft.State().String(),
+			Leader: f.Replica.Leader(),
+		}
+	}
+	f.Storage.FIMap.Range(func(key, value any) bool {
+		ret.Total.Files++
+		return true
+	})
+	ret.Version, ret.BuildDate = buildvers, builddate
+
+	WriteOK(w, r, &ret)
+}
+
+//go:embed dashboard.html
+var dashboard []byte
+
+// dashboardPage serves HTML page that shows cluster status.
+func dashboardPage(w http.ResponseWriter, r *http.Request) {
+	WriteHTMLHeader(w)
+	w.Write(dashboard)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"os"
-	"path"
 	"time"
 
 	"github.com/jessevdk/go-flags"
@@ -18,6 +17,11 @@ const (
 // CfgWebServ is web server settings.
 type CfgWebServ struct {
 	PortHTTP          []string      `json:"port-http" yaml:"port-http" env:"PORTHTTP" env-delim:";" short:"w" long:"http" description:"List of address:port values for non-encrypted connections. Address is skipped in most common cases, port only remains."`
+	PortTLS           []string      `json:"port-tls" yaml:"port-tls" env:"PORTTLS" env-delim:";" short:"s" long:"tls" description:"List of address:port values for encrypted TLS connections. Address is skipped in most common cases, port only remains."`
+	TLSCertFile       string        `json:"tls-cert-file" yaml:"tls-cert-file" env:"TLSCERTFILE" long:"tlscert" description:"PEM file with certificate chain for TLS connections."`
+	TLSKeyFile        string        `json:"tls-key-file" yaml:"tls-key-file" env:"TLSKEYFILE" long:"tlskey" description:"PEM file with private key for TLS connections."`
+	TLSReloadPeriod   time.Duration `json:"tls-reload-period" yaml:"tls-reload-period" long:"trp" description:"Period to check up TLS certificate files changes on disk to reload them."`
+	HTTPRedirect      bool          `json:"http-redirect" yaml:"http-redirect" long:"redirect" description:"Redirect all non-encrypted connections to first TLS port."`
 	ReadTimeout       time.Duration `json:"read-timeout" yaml:"read-timeout" long:"rt" description:"Maximum duration for reading the entire request, including the body."`
 	ReadHeaderTimeout time.Duration `json:"read-header-timeout" yaml:"read-header-timeout" long:"rht" description:"Amount of time allowed to read request headers."`
 	WriteTimeout      time.Duration `json:"write-timeout" yaml:"write-timeout" long:"wt" description:"Maximum duration before timing out writes of the response."`
@@ -30,14 +34,111 @@ type CfgWebServ struct {
 type CfgStorage struct {
 	NodeFluidFill    bool          `json:"node-fluid-fill" yaml:"node-fluid-fill" long:"nff" description:"Points to fill nodes by fluid algorithm."`
 	MinNodeChunkSize int64         `json:"min-node-chunk-size" yaml:"min-node-chunk-size" long:"mncs" description:"Minimum size of chunk to divide the file and put to nodes, except last chunk."`
+	StripeSize       int64         `json:"stripe-size" yaml:"stripe-size" long:"stripe" description:"Size of stripe units placed round-robin across nodes for large files, it's not less than minimum chunk size. Striping is off if it's zero."`
 	StreamChunkSize  int64         `json:"stream-chunk-size" yaml:"stream-chunk-size" long:"scs" description:"Maximum chunk size to send to each node during the streaming."`
 	ApiTimeout       time.Duration `json:"api-timeout" yaml:"api-timeout" long:"at" description:"gRPC API call timeout."`
+	HeartbeatPeriod  time.Duration `json:"heartbeat-period" yaml:"heartbeat-period" long:"hbp" description:"Period of nodes health checks."`
+	// Delays of reconnection to lost nodes, grows exponentially from base to maximum.
+	ReconnectBaseDelay time.Duration `json:"reconnect-base-delay" yaml:"reconnect-base-delay" long:"rbd" description:"Delay of first reconnection attempt to lost node."`
+	ReconnectMaxDelay  time.Duration `json:"reconnect-max-delay" yaml:"reconnect-max-delay" long:"rmd" description:"Upper bound of delay between reconnection attempts to lost node."`
+	DegradedStart      bool          `json:"degraded-start" yaml:"degraded-start" long:"dgs" description:"Start service with reachable nodes only, and connect others in background."`
+	StartupTimeout     time.Duration `json:"startup-timeout" yaml:"startup-timeout" long:"sut" description:"Maximum duration to wait for nodes at start."`
+}
+
+// CfgNodeTLS is TLS settings for gRPC connections to nodes.
+type CfgNodeTLS struct {
+	UseTLS   bool   `json:"use-tls" yaml:"use-tls" env:"NODETLS" long:"nodetls" description:"Use TLS for gRPC connections to nodes."`
+	CertFile string `json:"cert-file" yaml:"cert-file" env:"NODETLSCERT" long:"nodecert" description:"PEM file with client certificate presented to nodes for mutual TLS."`
+	KeyFile  string `json:"key-file" yaml:"key-file" env:"NODETLSKEY" long:"nodekey" description:"PEM file with private key of client certificate."`
+	CAFile   string `json:"ca-file" yaml:"ca-file" env:"NODETLSCA" long:"nodeca" description:"PEM file with CA certificates to verify nodes. System pool is used if it's not given."`
+}
+
+// CfgRegistry is settings of gRPC service where nodes register themselves.
+type CfgRegistry struct {
+	RegistryPort  string        `json:"port" yaml:"port" env:"REGISTRYPORT" long:"registry" description:"Address:port of gRPC registry where nodes register themselves. Registry is not served if it's not given."`
+	DepartTimeout time.Duration `json:"depart-timeout" yaml:"depart-timeout" long:"dpt" description:"Registered node is departed if it does not send heartbeats during this time."`
+	TokenFile     string        `json:"token-file" yaml:"token-file" env:"REGISTRYTOKENFILE" long:"regtoken" description:"File with shared token that nodes present to registry. Registry is served only if this token is given, or if nodes are verified by mutual TLS with CA."`
+}
+
+// CfgDiscovery is settings of nodes discovery, discovered nodes are added
+// to nodes from the list. Any of sources or both of them can be given.
+type CfgDiscovery struct {
+	DiscoveryFile   string        `json:"file" yaml:"file" env:"DISCOVERYFILE" long:"nodesfile" description:"YAML-file with list of nodes addresses, it's read again when modified."`
+	DiscoveryDNS    string        `json:"dns-name" yaml:"dns-name" env:"DISCOVERYDNS" long:"nodesdns" description:"DNS name to resolve nodes addresses. Name in form '_service._proto.domain' is resolved by SRV-records, any other name is resolved by A-records."`
+	DiscoveryPort   string        `json:"dns-port" yaml:"dns-port" env:"DISCOVERYPORT" long:"nodesport" description:"Port of nodes resolved by DNS A-records."`
+	DiscoveryPeriod time.Duration `json:"period" yaml:"period" long:"dsp" description:"Period of nodes discovery."`
+}
+
+// CfgRaft is settings of fronts cluster, where metadata is replicated
+// by Raft log. Fronts cluster is off if identity of front is not given.
+type CfgRaft struct {
+	RaftID    string   `json:"id" yaml:"id" env:"RAFTID" long:"raftid" description:"Base URL of REST API of this front, like 'http://front1:8008'. It's identity of front in cluster, and followers forward writes to this URL of leader."`
+	RaftAddr  string   `json:"addr" yaml:"addr" env:"RAFTADDR" long:"raftaddr" description:"Address:port of Raft transport of this front."`
+	RaftPeers []string `json:"peers" yaml:"peers" env:"RAFTPEERS" env-delim:";" long:"raftpeer" description:"All fronts of cluster including this one in form 'url=address:port', cluster is bootstrapped with them."`
+	RaftDir   string   `json:"dir" yaml:"dir" env:"RAFTDIR" long:"raftdir" description:"Directory where Raft log, term, vote and snapshots of this front are kept. It's required for fronts cluster."`
+}
+
+// CfgGC is settings of garbage collection of orphaned chunks at nodes.
+type CfgGC struct {
+	GCPeriod time.Duration `json:"period" yaml:"period" long:"gcp" description:"Period of garbage collection of orphaned chunks at nodes. Garbage is collected only by API call if it's zero."`
+	GCGrace  time.Duration `json:"grace" yaml:"grace" long:"gcg" description:"Chunks written during this time are not collected, it gives time to finish uploads."`
+	GCDryRun bool          `json:"dry-run" yaml:"dry-run" long:"gcdry" description:"Only find orphaned chunks and report about them, do not remove."`
+}
+
+// CfgLifecycle is settings of lifecycle rules, that move files
+// between storage classes by their age.
+type CfgLifecycle struct {
+	LifecyclePeriod time.Duration `json:"period" yaml:"period" long:"lcp" description:"Period of lifecycle rules applying. Rules are applied only by API call if it's zero."`
+	LifecycleRules  []string      `json:"rules" yaml:"rules" env:"LCRULES" env-delim:";" long:"lcrule" description:"Lifecycle rules in form 'from:to:age', file of storage class 'from' is moved to class 'to' when it's older than given age, like 'hot:cold:720h'."`
+}
+
+// CfgAuth is REST API authentication settings.
+// Authentication is disabled if no any credentials source is given.
+// Relative file paths are related to configuration path.
+type CfgAuth struct {
+	APIKeysFile       string        `json:"api-keys-file" yaml:"api-keys-file" env:"APIKEYSFILE" long:"apikeys" description:"YAML file with list of API keys and their owners."`
+	JWTSecretFile     string        `json:"jwt-secret-file" yaml:"jwt-secret-file" env:"JWTSECRETFILE" long:"jwtsecret" description:"File with secret to verify HS256 signed JWT."`
+	JWTPublicKeyFile  string        `json:"jwt-public-key-file" yaml:"jwt-public-key-file" env:"JWTPUBKEYFILE" long:"jwtpubkey" description:"PEM file with RSA public key to verify RS256 signed JWT."`
+	JWTIssuer         string        `json:"jwt-issuer" yaml:"jwt-issuer" long:"jwtiss" description:"Expected issuer of JWT, any issuer is accepted if it's empty."`
+	JWTAudience       string        `json:"jwt-audience" yaml:"jwt-audience" long:"jwtaud" description:"Expected audience of JWT, any audience is accepted if it's empty."`
+	PresignSecretFile string        `json:"presign-secret-file" yaml:"presign-secret-file" env:"PRESIGNSECRETFILE" long:"pssecret" description:"File with secret to sign URLs. Random secret is generated if it's not given, and signed URLs are valid only until service restart."`
+	PresignMaxTTL     time.Duration `json:"presign-max-ttl" yaml:"presign-max-ttl" long:"psttl" description:"Maximum expiry duration of signed URLs."`
+	CfgRoles          `json:"roles" yaml:"roles" group:"Roles"`
+}
+
+// CfgRoles is lists of users granted by each role. Entries with "group:"
+// prefix points to user groups. Each role includes all lower roles.
+type CfgRoles struct {
+	Readers []string `json:"readers" yaml:"readers" env:"READERS" env-delim:";" long:"readers" description:"Users and groups who can read files and storage state."`
+	Writers []string `json:"writers" yaml:"writers" env:"WRITERS" env-delim:";" long:"writers" description:"Users and groups who can upload and remove files."`
+	Admins  []string `json:"admins" yaml:"admins" env:"ADMINS" env-delim:";" long:"admins" description:"Users and groups who can perform cluster operations."`
+}
+
+// CfgTracing is OpenTelemetry tracing settings.
+type CfgTracing struct {
+	Exporter string `json:"exporter" yaml:"exporter" env:"TRACEEXPORTER" long:"trace" choice:"" choice:"stdout" choice:"otlp" description:"Exporter of spans, 'stdout' or 'otlp'. Spans are not recorded if it's empty."`
+	Endpoint string `json:"endpoint" yaml:"endpoint" env:"TRACEENDPOINT" long:"traceendpoint" description:"Address of OTLP collector, 'host:port' for TLS connection or URL with 'http://' scheme for plain connection."`
+}
+
+// CfgLog is logging settings.
+type CfgLog struct {
+	Format string `json:"format" yaml:"format" env:"LOGFORMAT" long:"logformat" choice:"text" choice:"json" description:"Format of log records, 'text' or 'json'."`
+	Level  string `json:"level" yaml:"level" env:"LOGLEVEL" long:"loglevel" choice:"debug" choice:"info" choice:"warn" choice:"error" description:"Minimum level of log records."`
 }
 
 // Config is common service settings.
 type Config struct {
-	CfgWebServ `json:"web-server" yaml:"web-server" group:"Web Server"`
-	CfgStorage `json:"storage" yaml:"storage" group:"Storage"`
+	CfgWebServ   `json:"web-server" yaml:"web-server" group:"Web Server"`
+	CfgStorage   `json:"storage" yaml:"storage" group:"Storage"`
+	CfgNodeTLS   `json:"node-tls" yaml:"node-tls" group:"Nodes TLS"`
+	CfgRegistry  `json:"registry" yaml:"registry" group:"Registry"`
+	CfgDiscovery `json:"discovery" yaml:"discovery" group:"Discovery"`
+	CfgRaft      `json:"cluster" yaml:"cluster" group:"Fronts cluster"`
+	CfgGC        `json:"gc" yaml:"gc" group:"Garbage collection"`
+	CfgLifecycle `json:"lifecycle" yaml:"lifecycle" group:"Lifecycle"`
+	CfgAuth      `json:"authentication" yaml:"authentication" group:"Authentication"`
+	CfgTracing   `json:"tracing" yaml:"tracing" group:"Tracing"`
+	CfgLog       `json:"log" yaml:"log" group:"Logging"`
 	// list of nodes
 	NodeList []string `json:"node-list" yaml:"node-list" env:"NODELIST" env-delim:";" short:"n" long:"node" description:"Distributed file server list of nodes."`
 }
@@ -46,6 +147,7 @@ type Config struct {
 var cfg = Config{ // inits default values:
 	CfgWebServ: CfgWebServ{
 		PortHTTP:          []string{":8008", ":8010"},
+		TLSReloadPeriod:   time.Duration(60) * time.Second,
 		ReadTimeout:       time.Duration(15) * time.Second,
 		ReadHeaderTimeout: time.Duration(15) * time.Second,
 		WriteTimeout:      time.Duration(15) * time.Second,
@@ -54,23 +156,51 @@ var cfg = Config{ // inits default values:
 		ShutdownTimeout:   time.Duration(15) * time.Second,
 	},
 	CfgStorage: CfgStorage{
-		NodeFluidFill:    true,
-		MinNodeChunkSize: 4 * 1024,
-		StreamChunkSize:  1024,
-		ApiTimeout:       2 * time.Second,
+		NodeFluidFill:      true,
+		MinNodeChunkSize:   4 * 1024,
+		StreamChunkSize:    1024,
+		ApiTimeout:         2 * time.Second,
+		HeartbeatPeriod:    5 * time.Second,
+		ReconnectBaseDelay: time.Second,
+		ReconnectMaxDelay:  30 * time.Second,
+		StartupTimeout:     10 * time.Second,
+	},
+	CfgRegistry: CfgRegistry{
+		DepartTimeout: 15 * time.Second,
+	},
+	CfgDiscovery: CfgDiscovery{
+		DiscoveryPort:   "50051",
+		DiscoveryPeriod: 10 * time.Second,
+	},
+	CfgGC: CfgGC{
+		GCPeriod: time.Hour,
+		GCGrace:  15 * time.Minute,
+	},
+	CfgLifecycle: CfgLifecycle{
+		LifecyclePeriod: time.Hour,
+	},
+	CfgAuth: CfgAuth{
+		PresignMaxTTL: 7 * 24 * time.Hour,
+	},
+	CfgLog: CfgLog{
+		Format: "text",
+		Level:  "info",
 	},
 	NodeList: []string{"localhost:50051", "localhost:50052"},
 }
 
 // compiled binary version, sets by compiler with command
-//    go build -ldflags="-X 'main.buildvers=%buildvers%'"
+//
+//	go build -ldflags="-X 'main.buildvers=%buildvers%'"
 var buildvers string
 
 // compiled binary build date, sets by compiler with command
-//    go build -ldflags="-X 'main.builddate=%date%'"
+//
+//	go build -ldflags="-X 'main.builddate=%date%'"
 var builddate string
 
-func init() {
+// ParseConfig reads settings from command line and environment variables.
+func ParseConfig() {
 	if _, err := flags.Parse(&cfg); err != nil {
 		os.Exit(1)
 	}
@@ -79,7 +209,7 @@ func init() {
 // ReadYaml reads "data" object from YAML-file with given file name.
 func ReadYaml(fname string, data interface{}) (err error) {
 	var body []byte
-	if body, err = os.ReadFile(path.Join(ConfigPath, fname)); err != nil {
+	if body, err = os.ReadFile(CfgPath(fname)); err != nil {
 		return
 	}
 	if err = yaml.Unmarshal(body, data); err != nil {
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoDNSPort is "port of nodes found by DNS A-records is not given" error message.
+var ErrNoDNSPort = errors.New("port of nodes found by DNS A-records is not given")
+
+// Discovery is source of nodes addresses.
+type Discovery interface {
+	// Nodes returns current list of nodes addresses in "host:port" form.
+	Nodes(ctx context.Context) ([]string, error)
+}
+
+// FileDiscovery takes nodes addresses from YAML-file with list of
+// "host:port" strings. File is read again only if it was modified.
+type FileDiscovery struct {
+	Path string
+
+	modtime time.Time
+	list    []string
+	mux     sync.Mutex
+}
+
+// Nodes is Discovery interface implementation.
+func (d *FileDiscovery) Nodes(ctx context.Context) (list []string, err error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	var fi os.FileInfo
+	if fi, err = os.Stat(CfgPath(d.Path)); err != nil {
+		return
+	}
+	if fi.ModTime().Equal(d.modtime) {
+		return d.list, nil
+	}
+	if err = ReadYaml(d.Path, &list); err != nil {
+		return
+	}
+	d.modtime, d.list = fi.ModTime(), list
+	slog.Info("nodes file loaded", "file", d.Path, "count", len(list))
+	return
+}
+
+// Resolver is a subset of net.Resolver methods used by nodes discovery.
+// It can be replaced by local stand-in.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// DNSDiscovery resolves nodes addresses by service name. Name in form
+// "_service._proto.domain" is resolved by SRV-records with ports,
+// any other name is resolved by A-records, and given port is used.
+type DNSDiscovery struct {
+	Name     string
+	Port     string
+	Resolver Resolver
+}
+
+// Nodes is Discovery interface implementation.
+func (d *DNSDiscovery) Nodes(ctx context.Context) (list []string, err error) {
+	if strings.HasPrefix(d.Name, "_") {
+		var srvs []*net.SRV
+		if _, srvs, err = d.Resolver.LookupSRV(ctx, "", "", d.Name); err != nil {
+			return
+		}
+		for _, srv := range srvs {
+			var host = strings.TrimSuffix(srv.Target, ".")
+			list = append(list, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		}
+		return
+	}
+
+	if d.Port == "" {
+		err = ErrNoDNSPort
+		return
+	}
+	var addrs []string
+	if addrs, err = d.Resolver.LookupHost(ctx, d.Name); err != nil {
+		return
+	}
+	for _, addr := range addrs {
+		list = append(list, net.JoinHostPort(addr, d.Port))
+	}
+	return
+}
+
+// MultiDiscovery joins nodes lists of several sources.
+type MultiDiscovery []Discovery
+
+// Nodes is Discovery interface implementation.
+func (md MultiDiscovery) Nodes(ctx context.Context) (list []string, err error) {
+	for _, d := range md {
+		var sub []string
+		if sub, err = d.Nodes(ctx); err != nil {
+			return
+		}
+		list = append(list, sub...)
+	}
+	return
+}
+
+// NodeKeys returns keys to compare addresses of nodes, that are "ip:port"
+// for all IP addresses of host. So node given by host name and by its IP
+// address has common key. Address itself is the key if it can not be resolved.
+func NodeKeys(ctx context.Context, res Resolver, addr string) []string {
+	var host, port, err = net.SplitHostPort(addr)
+	if err != nil {
+		return []string{addr}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{net.JoinHostPort(ip.String(), port)}
+	}
+	var ips []string
+	if ips, err = res.LookupHost(ctx, host); err != nil || len(ips) == 0 {
+		return []string{strings.ToLower(addr)}
+	}
+	var keys = make([]string, len(ips))
+	for i, s := range ips {
+		if ip := net.ParseIP(s); ip != nil {
+			s = ip.String()
+		}
+		keys[i] = net.JoinHostPort(s, port)
+	}
+	return keys
+}
+
+// Discover adds new nodes received from discovery, and marks nodes that
+// are absent in list now as departed, so files are not placed to them.
+// Departed nodes are kept in the list, because stored files refer to nodes
+// by index. Nodes from given static list and nodes registered by registry
+// are never departed by discovery. Addresses are compared by IP addresses
+// of hosts, so node is not added twice by host name and by IP address.
+func (f *Front) Discover(d Discovery, static []string) {
+	var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+	defer cancel()
+	var list, err = d.Nodes(ctx)
+	if err != nil {
+		// keep nodes as is until discovery will be restored
+		slog.Warn("nodes discovery failed", "error", err)
+		return
+	}
+
+	f.Storage.nodmux.RLock()
+	var nodes = f.Storage.Nodes
+	f.Storage.nodmux.RUnlock()
+	var known = map[string]*NodeInfo{}
+	for _, node := range nodes {
+		for _, key := range NodeKeys(ctx, f.Resolver, node.Addr) {
+			known[key] = node
+		}
+	}
+	var find = func(addr string) (node *NodeInfo, keys []string) {
+		keys = NodeKeys(ctx, f.Resolver, addr)
+		for _, key := range keys {
+			if node = known[key]; node != nil {
+				return
+			}
+		}
+		return
+	}
+
+	var found = map[*NodeInfo]bool{}
+	for _, addr := range list {
+		var node, keys = find(addr)
+		if node == nil {
+			// new nodes are added by leader if fronts cluster is on
+			var res, err = f.ApplyCmd(&Command{Op: OpAddNode, Addr: addr})
+			if err != nil {
+				slog.Debug("discovered node is not added", "addr", addr, "error", err)
+				continue
+			}
+			var added = res.(NodeAdded)
+			node = added.Node
+			for _, key := range keys {
+				known[key] = node
+			}
+			slog.Info("node discovered", "idx", added.Idx, "addr", addr)
+		} else if !found[node] && node.departed.Swap(false) {
+			slog.Info("departed node is back", "addr", node.Addr)
+		}
+		found[node] = true
+	}
+
+	for _, addr := range static {
+		if node, _ := find(addr); node != nil {
+			found[node] = true
+		}
+	}
+	for _, node := range nodes {
+		if found[node] {
+			continue
+		}
+		node.hbmux.RLock()
+		var registered = !node.regbeat.IsZero()
+		node.hbmux.RUnlock()
+		if !registered && !node.departed.Swap(true) {
+			slog.Warn("node departed", "addr", node.Addr, "reason", "absent at discovery")
+		}
+	}
+}
+
+// RunDiscovery makes first nodes discovery at once if any discovery
+// source is given, and then repeats it with discovery period until exit.
+func (f *Front) RunDiscovery() {
+	var md MultiDiscovery
+	if cfg.DiscoveryFile != "" {
+		md = append(md, &FileDiscovery{Path: cfg.DiscoveryFile})
+	}
+	if cfg.DiscoveryDNS != "" {
+		md = append(md, &DNSDiscovery{
+			Name:     cfg.DiscoveryDNS,
+			Port:     cfg.DiscoveryPort,
+			Resolver: f.Resolver,
+		})
+	}
+	if len(md) == 0 {
+		return
+	}
+
+	f.Discover(md, cfg.NodeList)
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		var ticker = time.NewTicker(cfg.DiscoveryPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case <-ticker.C:
+				f.Discover(md, cfg.NodeList)
+			}
+		}
+	}()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+)
+
+// testResolver is stand-in of DNS resolver with given records.
+type testResolver struct {
+	Hosts map[string][]string   // A-records by host name
+	SRV   map[string][]*net.SRV // SRV-records by service name
+}
+
+func (r *testResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.Hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r *testResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if srvs, ok := r.SRV[name]; ok {
+		return name, srvs, nil
+	}
+	return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestDNSDiscovery(t *testing.T) {
+	var res = &testResolver{
+		Hosts: map[string][]string{
+			"nodes": {"10.0.0.1", "10.0.0.2"},
+		},
+		SRV: map[string][]*net.SRV{
+			"_dfs._tcp.local": {
+				{Target: "node1.local.", Port: 50051},
+				{Target: "node2.local.", Port: 50052},
+			},
+		},
+	}
+	var tests = []struct {
+		name, port string
+		list       []string
+		err        error
+	}{
+		{"nodes", "50051", []string{"10.0.0.1:50051", "10.0.0.2:50051"}, nil},
+		{"nodes", "", nil, ErrNoDNSPort},
+		{"_dfs._tcp.local", "", []string{"node1.local:50051", "node2.local:50052"}, nil},
+	}
+	for _, test := range tests {
+		var d = &DNSDiscovery{Name: test.name, Port: test.port, Resolver: res}
+		var list, err = d.Nodes(context.Background())
+		if !errors.Is(err, test.err) {
+			t.Errorf("name %s: expected error %v, got %v", test.name, test.err, err)
+		}
+		if !slices.Equal(list, test.list) {
+			t.Errorf("name %s: expected %v, got %v", test.name, test.list, list)
+		}
+	}
+}
+
+func TestNodeKeys(t *testing.T) {
+	var res = &testResolver{
+		Hosts: map[string][]string{
+			"node1": {"10.0.0.1", "::ffff:10.0.0.11"},
+		},
+	}
+	var tests = []struct {
+		addr string
+		keys []string
+	}{
+		{"node1:50051", []string{"10.0.0.1:50051", "10.0.0.11:50051"}},
+		{"10.0.0.1:50051", []string{"10.0.0.1:50051"}},
+		{"[::ffff:10.0.0.1]:50051", []string{"10.0.0.1:50051"}},
+		{"Unknown:50051", []string{"unknown:50051"}},
+		{"noport", []string{"noport"}},
+	}
+	for _, test := range tests {
+		if keys := NodeKeys(context.Background(), res, test.addr); !slices.Equal(keys, test.keys) {
+			t.Errorf("address %s: expected %v, got %v", test.addr, test.keys, keys)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	StartExit(t)
+	var res = &testResolver{
+		Hosts: map[string][]string{
+			"node1": {"10.0.0.1"},
+			"node2": {"10.0.0.2"},
+			"node3": {"10.0.0.3"},
+		},
+		SRV: map[string][]*net.SRV{},
+	}
+	var f = NewFront()
+	f.Resolver = res
+	var d = &DNSDiscovery{Name: "_dfs._tcp.local", Resolver: res}
+	var static = []string{"node1:50051"}
+	f.RunNodeList(static)
+	var check = func(step string, addrs []string, departed []bool) {
+		t.Helper()
+		if list := NodeAddrs(f.Storage); !slices.Equal(list, addrs) {
+			t.Fatalf("%s: expected nodes %v, got %v", step, addrs, list)
+		}
+		for i, node := range f.Storage.Nodes {
+			if node.departed.Load() != departed[i] {
+				t.Errorf("%s: node %s departed is %t", step, node.Addr, !departed[i])
+			}
+		}
+	}
+
+	// node from list is found by IP address, and is not added twice
+	res.SRV[d.Name] = []*net.SRV{
+		{Target: "10.0.0.1", Port: 50051},
+		{Target: "node2.", Port: 50051},
+		{Target: "10.0.0.2", Port: 50051},
+	}
+	f.Discover(d, static)
+	check("discovered", []string{"node1:50051", "node2:50051"}, []bool{false, false})
+
+	// vanished node is departed, node from list is kept
+	res.SRV[d.Name] = []*net.SRV{
+		{Target: "node3", Port: 50051},
+	}
+	f.Discover(d, static)
+	check("vanished", []string{"node1:50051", "node2:50051", "node3:50051"}, []bool{false, true, false})
+	for _, node := range f.Storage.Nodes {
+		node.ready.Store(true) // as if connected
+	}
+	if ids := f.Storage.AvailableNodes(); !slices.Equal(ids, []int64{0, 2}) {
+		t.Errorf("expected nodes %v available for placement, got %v", []int64{0, 2}, ids)
+	}
+
+	// departed node is back by other address of the same host
+	res.SRV[d.Name] = []*net.SRV{
+		{Target: "10.0.0.2", Port: 50051},
+		{Target: "node3", Port: 50051},
+	}
+	f.Discover(d, static)
+	check("back", []string{"node1:50051", "node2:50051", "node3:50051"}, []bool{false, false, false})
+
+	// nodes are kept as is if discovery fails
+	delete(res.SRV, d.Name)
+	f.Discover(d, static)
+	check("failed", []string{"node1:50051", "node2:50051", "node3:50051"}, []bool{false, false, false})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StartExit creates exit context for test, and on test cleanup
+// sends exit signal and waits until all server threads will be stopped.
+func StartExit(t *testing.T) {
+	t.Helper()
+	exitctx, exitfn = context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		exitfn()
+		exitwg.Wait()
+	})
+}
+
+// StartTestFront starts front without fronts cluster, with given number
+// of test nodes, and waits until nodes will be connected. REST API of
+// front is served by returned test server.
+func StartTestFront(t *testing.T, num int) (*Front, *httptest.Server) {
+	t.Helper()
+	var f = NewFront()
+	var list = make([]string, num)
+	for i := range list {
+		list[i] = StartTestNode(t).Addr
+	}
+	f.Storage.RunNodes()
+	f.RunNodeList(list)
+	WaitFor(t, 10*time.Second, "nodes connection", func() bool {
+		return len(f.Storage.AvailableNodes()) == num
+	})
+	var gmux = NewRouter()
+	f.RegisterRoutes(gmux)
+	var server = httptest.NewServer(gmux)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+// testUser is user of REST API in tests, API key of user is its name.
+type testUser struct {
+	Name   string
+	Role   Role
+	Groups []string
+}
+
+// StartTestAuth enables authentication by API keys for given users.
+// Authentication is disabled on test cleanup.
+func StartTestAuth(t *testing.T, users ...testUser) {
+	t.Helper()
+	var saved = cfg.CfgAuth
+	var list []APIKey
+	cfg.Readers, cfg.Writers, cfg.Admins = nil, nil, nil
+	for _, user := range users {
+		list = append(list, APIKey{Key: user.Name, Name: user.Name, Groups: user.Groups})
+		switch user.Role {
+		case RoleReader:
+			cfg.Readers = append(cfg.Readers, user.Name)
+		case RoleWriter:
+			cfg.Writers = append(cfg.Writers, user.Name)
+		case RoleAdmin:
+			cfg.Admins = append(cfg.Admins, user.Name)
+		}
+	}
+	var body, _ = yaml.Marshal(list)
+	cfg.APIKeysFile = filepath.Join(t.TempDir(), "keys.yaml")
+	if err := os.WriteFile(cfg.APIKeysFile, body, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Load(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfg.CfgAuth = saved
+		auth.Load()
+	})
+}
+
+// CallAPI posts given JSON arguments to REST API by given API key, and
+// decodes JSON response to given value if status is OK. Value can be nil.
+// Key is not sent if it's empty. Returns status of response.
+func CallAPI(t *testing.T, url, key, arg string, ret any) int {
+	t.Helper()
+	var req, _ = http.NewRequest("POST", url, strings.NewReader(arg))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	var resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && ret != nil {
+		if err = json.NewDecoder(resp.Body).Decode(ret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// UploadFile uploads file with given name and content by given API key,
+// and returns status of response and information of uploaded file.
+// URL is full URL of upload API with arguments, key is not sent if it's empty.
+func UploadFile(t *testing.T, url, key, name string, content []byte) (status int, info FileInfo) {
+	t.Helper()
+	var body bytes.Buffer
+	var mw = multipart.NewWriter(&body)
+	var fw, _ = mw.CreateFormFile("datafile", name)
+	fw.Write(content)
+	mw.Close()
+	var req, _ = http.NewRequest("POST", url, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	var resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, info
+}
+
+// NodeAddrs returns addresses of nodes of storage in order of indexes.
+func NodeAddrs(s *Storage) (list []string) {
+	s.nodmux.RLock()
+	defer s.nodmux.RUnlock()
+	for _, node := range s.Nodes {
+		list = append(list, node.Addr)
+	}
+	return
+}
+
+// WaitFor checks up given condition until it's true, or fails test by timeout.
+func WaitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	var deadline = time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout on waiting %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
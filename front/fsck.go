@@ -0,0 +1,390 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Kinds of problems found by consistency check.
+const (
+	FsckMissing     = "missing"     // chunk is absent at node
+	FsckSize        = "size"        // chunk at node has other bounds than in metadata
+	FsckTiling      = "tiling"      // chunks of file do not tile it from 0 to size
+	FsckChecksum    = "checksum"    // content of file does not match its checksum
+	FsckUnavailable = "unavailable" // node of chunk is unavailable, chunk is not checked
+)
+
+var (
+	// ErrFsckRunning is "consistency check is already in progress" error message.
+	ErrFsckRunning = errors.New("consistency check is already in progress")
+	// ErrChunkLost is "chunk is absent at node, it can not be repaired" error message.
+	ErrChunkLost = errors.New("chunk is absent at node, it can not be repaired")
+)
+
+// FsckProblem is problem of file found by consistency check.
+type FsckProblem struct {
+	FileID int64     `json:"file_id" yaml:"file_id" xml:"file_id"`
+	Bucket string    `json:"bucket" yaml:"bucket" xml:"bucket"`
+	Name   string    `json:"name" yaml:"name" xml:"name"`
+	Kind   string    `json:"kind" yaml:"kind" xml:"kind"`
+	What   string    `json:"what" yaml:"what" xml:"what"`
+	Chunk  *pb.Range `json:"chunk,omitempty" yaml:"chunk,omitempty" xml:"chunk,omitempty"` // chunk with problem
+}
+
+// FsckNode is statistics counters of node compared with files metadata,
+// and with content listed by node if node can list its chunks.
+type FsckNode struct {
+	Idx          int    `json:"idx" yaml:"idx" xml:"idx"`
+	Addr         string `json:"addr" yaml:"addr" xml:"addr"`
+	Size         int64  `json:"size" yaml:"size" xml:"size"`                                                          // size counter
+	Chunks       int    `json:"chunks" yaml:"chunks" xml:"chunks"`                                                    // chunks counter
+	ExpSize      int64  `json:"exp_size" yaml:"exp_size" xml:"exp_size"`                                              // size by metadata
+	ExpChunks    int    `json:"exp_chunks" yaml:"exp_chunks" xml:"exp_chunks"`                                        // chunks by metadata
+	StoredSize   int64  `json:"stored_size,omitempty" yaml:"stored_size,omitempty" xml:"stored_size,omitempty"`       // size listed by node
+	StoredChunks int    `json:"stored_chunks,omitempty" yaml:"stored_chunks,omitempty" xml:"stored_chunks,omitempty"` // chunks listed by node
+	Listed       bool   `json:"listed" yaml:"listed" xml:"listed"`                                                    // node has listed its chunks
+	Mismatch     bool   `json:"mismatch,omitempty" yaml:"mismatch,omitempty" xml:"mismatch,omitempty"`
+	Repaired     bool   `json:"repaired,omitempty" yaml:"repaired,omitempty" xml:"repaired,omitempty"`
+}
+
+// FsckReport is result of consistency check of files metadata and nodes.
+type FsckReport struct {
+	XMLName xml.Name `json:"-" yaml:"-" xml:"fsck"`
+
+	Repair   bool          `json:"repair" yaml:"repair" xml:"repair"`
+	Verify   bool          `json:"verify" yaml:"verify" xml:"verify"`
+	Start    unix_t        `json:"start" yaml:"start" xml:"start"`
+	Finish   unix_t        `json:"finish" yaml:"finish" xml:"finish"`
+	Files    int           `json:"files" yaml:"files" xml:"files"`
+	Chunks   int           `json:"chunks" yaml:"chunks" xml:"chunks"`
+	Repaired int           `json:"repaired" yaml:"repaired" xml:"repaired"`
+	Problems []FsckProblem `json:"problems" yaml:"problems" xml:"problems>problem"`
+	Nodes    []FsckNode    `json:"nodes" yaml:"nodes" xml:"nodes>node"`
+}
+
+// CountChunks returns size and number of chunks at each node
+// counted by files metadata.
+func (s *Storage) CountChunks() (sizes []int64, nums []int) {
+	s.nodmux.RLock()
+	var n = len(s.Nodes)
+	s.nodmux.RUnlock()
+	sizes, nums = make([]int64, n), make([]int, n)
+	s.FIMap.Range(func(key, value any) bool {
+		for _, rng := range value.(*FileInfo).Chunks {
+			if rng.NodeId < int64(n) {
+				sizes[rng.NodeId] += rng.To - rng.From
+				nums[rng.NodeId]++
+			}
+		}
+		return true
+	})
+	return
+}
+
+// Recount sets statistics counters of nodes to values counted by files metadata.
+func (s *Storage) Recount() {
+	var sizes, nums = s.CountChunks()
+	s.nodmux.Lock()
+	defer s.nodmux.Unlock()
+	for i := range sizes {
+		s.Nodes[i].SumSize, s.Nodes[i].NumChunks = sizes[i], nums[i]
+	}
+}
+
+// CheckTiling returns error if chunks of file do not cover
+// its content from 0 to size without gaps and overlaps.
+func (fi *FileInfo) CheckTiling() error {
+	var chunks = slices.Clone(fi.Chunks)
+	slices.SortFunc(chunks, func(a, b *pb.Range) int {
+		return cmp.Compare(a.From, b.From)
+	})
+	var pos int64
+	for _, rng := range chunks {
+		if rng.From != pos {
+			return fmt.Errorf("chunk %d-%d starts at %d, expected %d", rng.From, rng.To, rng.From, pos)
+		}
+		if rng.To < rng.From {
+			return fmt.Errorf("chunk %d-%d has negative size", rng.From, rng.To)
+		}
+		pos = rng.To
+	}
+	if pos != fi.Size {
+		return fmt.Errorf("chunks cover %d bytes of %d", pos, fi.Size)
+	}
+	return nil
+}
+
+// VerifyChecksum reads content of file and compares it with file checksum.
+func (s *Storage) VerifyChecksum(ctx context.Context, fi *FileInfo) (err error) {
+	var h = sha256.New()
+	if _, err = io.Copy(h, s.NewReader(ctx, fi)); err != nil {
+		return
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != fi.Checksum {
+		err = fmt.Errorf("content has checksum %s, expected %s", sum, fi.Checksum)
+	}
+	return
+}
+
+// CopyTimeout returns timeout to copy chunk of given size, it's API
+// timeout for each piece of streaming chunk size.
+func CopyTimeout(size int64) time.Duration {
+	var pieces = max((size+cfg.StreamChunkSize-1)/cfg.StreamChunkSize, 1)
+	return cfg.ApiTimeout * time.Duration(pieces)
+}
+
+// CopyChunk reads content of chunk with given range from source node
+// and writes it to destination node as chunk with `to` range, it has
+// the same bounds and can have other file ID. Content is read and
+// written by pieces of streaming chunk size, so chunks of any size fit
+// to gRPC messages limits. Copying is limited by timeout that scales
+// with chunk size.
+func CopyChunk(ctx context.Context, src, dst *NodeInfo, rng, to *pb.Range) (err error) {
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(ctx, CopyTimeout(rng.To-rng.From))
+	defer cancel()
+
+	var stream pb.DataGuide_WriteClient
+	if stream, err = dst.Client.Write(ctx); err != nil {
+		return
+	}
+	for pos := rng.From; pos < rng.To; pos += cfg.StreamChunkSize {
+		var chunk *pb.Chunk
+		if chunk, err = src.Client.Read(ctx, &pb.Range{
+			NodeId: rng.NodeId,
+			FileId: rng.FileId,
+			From:   pos,
+			To:     min(pos+cfg.StreamChunkSize, rng.To),
+		}); err != nil {
+			return
+		}
+		if chunk.Range == nil {
+			err = ErrNRNoChunk
+			return
+		}
+		if err = stream.Send(&pb.Chunk{
+			Range: &pb.Range{
+				NodeId: to.NodeId,
+				FileId: to.FileId,
+				From:   chunk.Range.From,
+				To:     chunk.Range.To,
+			},
+			Value: chunk.Value,
+		}); err != nil {
+			return
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	return
+}
+
+// fsck is state of consistency check.
+type fsck struct {
+	s      *Storage
+	rep    *FsckReport
+	nodes  []*NodeInfo
+	lists  [][]*pb.ChunkInfo // chunks listed by each node
+	listed []bool            // node has listed its chunks
+}
+
+// problem adds problem of file to report.
+func (c *fsck) problem(fi *FileInfo, kind string, rng *pb.Range, err error) {
+	c.rep.Problems = append(c.rep.Problems, FsckProblem{
+		FileID: fi.FileID,
+		Bucket: fi.Bucket,
+		Name:   fi.Name,
+		Kind:   kind,
+		What:   err.Error(),
+		Chunk:  rng,
+	})
+}
+
+// checkFile checks up chunks of file at nodes, and its checksum.
+func (c *fsck) checkFile(fi *FileInfo) {
+	c.rep.Files++
+	c.rep.Chunks += len(fi.Chunks)
+	if err := fi.CheckTiling(); err != nil {
+		c.problem(fi, FsckTiling, nil, err)
+	}
+
+	var intact = true
+	for _, rng := range fi.Chunks {
+		if rng.To == rng.From {
+			continue // empty chunks are not stored
+		}
+		if rng.NodeId >= int64(len(c.nodes)) || !c.nodes[rng.NodeId].Available() {
+			c.problem(fi, FsckUnavailable, rng, ErrNodeUnavailable)
+			intact = false
+			continue
+		}
+		var node = c.nodes[rng.NodeId]
+		var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+		var res, err = node.Client.GetRange(ctx, rng.ChunkID())
+		cancel()
+		if err != nil {
+			c.problem(fi, FsckUnavailable, rng, err)
+			intact = false
+			continue
+		}
+		if res.FileId == 0 {
+			// storage keeps single copy of each chunk,
+			// so there is no source to restore it from
+			c.problem(fi, FsckMissing, rng, ErrChunkLost)
+			intact = false
+			continue
+		}
+		if res.From != rng.From || res.To != rng.To {
+			c.problem(fi, FsckSize, rng, fmt.Errorf("node has chunk %d-%d, expected %d-%d",
+				res.From, res.To, rng.From, rng.To))
+		}
+	}
+
+	// content can be read only if all chunks are present
+	if c.rep.Verify && intact && fi.Checksum != "" {
+		if err := c.s.VerifyChecksum(exitctx, fi); err != nil {
+			c.problem(fi, FsckChecksum, nil, err)
+		}
+	}
+}
+
+// CheckConsistency walks through all files and checks up their chunks
+// at nodes, and compares statistics counters of nodes with metadata and
+// with content listed by nodes. In repair mode counters are recounted
+// by metadata. Lost chunks can not be repaired, storage keeps single
+// copy of each chunk. With verify option content of files is compared
+// with checksums.
+func (f *Front) CheckConsistency(repair, verify bool) (rep *FsckReport, err error) {
+	if !f.fsckrunning.CompareAndSwap(false, true) {
+		err = ErrFsckRunning
+		return
+	}
+	defer f.fsckrunning.Store(false)
+
+	var c = fsck{
+		s: f.Storage,
+		rep: &FsckReport{
+			Repair:   repair,
+			Verify:   verify,
+			Start:    UnixJSNow(),
+			Problems: []FsckProblem{},
+		},
+	}
+	f.Storage.nodmux.RLock()
+	c.nodes = f.Storage.Nodes
+	f.Storage.nodmux.RUnlock()
+
+	// get real content of nodes
+	c.lists = make([][]*pb.ChunkInfo, len(c.nodes))
+	c.listed = make([]bool, len(c.nodes))
+	for i, node := range c.nodes {
+		if !node.Available() || !node.HasFeature(pb.FeatureList) {
+			continue
+		}
+		var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+		if list, err := node.Client.List(ctx, &emptypb.Empty{}); err == nil {
+			c.lists[i], c.listed[i] = list.Chunks, true
+		}
+		cancel()
+	}
+
+	f.Storage.FIMap.Range(func(key, value any) bool {
+		c.checkFile(value.(*FileInfo))
+		return exitctx.Err() == nil
+	})
+
+	// compare counters with metadata, and with content of nodes
+	var sizes, nums = f.Storage.CountChunks()
+	var mismatch bool
+	f.Storage.nodmux.RLock()
+	for i := range sizes {
+		var node = f.Storage.Nodes[i]
+		var fn = FsckNode{
+			Idx:       i,
+			Addr:      node.Addr,
+			Size:      node.SumSize,
+			Chunks:    node.NumChunks,
+			ExpSize:   sizes[i],
+			ExpChunks: nums[i],
+		}
+		if i < len(c.lists) && c.listed[i] {
+			fn.Listed = true
+			for _, ci := range c.lists[i] {
+				fn.StoredSize += ci.Range.To - ci.Range.From
+				fn.StoredChunks++
+			}
+		}
+		var counted = fn.Size != fn.ExpSize || fn.Chunks != fn.ExpChunks
+		var stored = fn.Listed && (fn.StoredSize != fn.Size || fn.StoredChunks != fn.Chunks)
+		fn.Mismatch = counted || stored
+		mismatch = mismatch || counted
+		c.rep.Nodes = append(c.rep.Nodes, fn)
+	}
+	f.Storage.nodmux.RUnlock()
+	if repair && mismatch {
+		if _, err = f.ApplyCmd(&Command{Op: OpRecount}); err != nil {
+			return
+		}
+		// counters are repaired if node content agrees with metadata,
+		// otherwise node has lost chunks, or garbage to collect
+		for i := range c.rep.Nodes {
+			var fn = &c.rep.Nodes[i]
+			if !fn.Mismatch || fn.Listed && (fn.StoredSize != fn.ExpSize || fn.StoredChunks != fn.ExpChunks) {
+				continue
+			}
+			fn.Repaired = true
+			c.rep.Repaired++
+		}
+	}
+
+	c.rep.Finish = UnixJSNow()
+	rep = c.rep
+	slog.Info("consistency check complete", "repair", repair, "verify", verify,
+		"files", rep.Files, "problems", len(rep.Problems), "repaired", rep.Repaired)
+	return
+}
+
+// fsckAPI checks up consistency of files metadata and nodes content.
+// Optional "repair" argument points to repair found problems where
+// it's possible, and "verify" argument points to check up content
+// of files by checksums.
+func (f *Front) fsckAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var repair, verify bool
+	if s := r.FormValue("repair"); len(s) > 0 {
+		if repair, err = strconv.ParseBool(s); err != nil {
+			WriteError400(w, r, err, AECfsckbadrepair)
+			return
+		}
+	}
+	if s := r.FormValue("verify"); len(s) > 0 {
+		if verify, err = strconv.ParseBool(s); err != nil {
+			WriteError400(w, r, err, AECfsckbadverify)
+			return
+		}
+	}
+
+	var rep *FsckReport
+	if rep, err = f.CheckConsistency(repair, verify); err != nil {
+		if errors.Is(err, ErrFsckRunning) {
+			WriteError(w, r, http.StatusConflict, err, AECfsckbusy)
+		} else {
+			WriteError(w, r, http.StatusServiceUnavailable, err, AECfsckapply)
+		}
+		return
+	}
+	WriteOK(w, r, rep)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var (
+	// ErrGCRunning is "garbage collection is already in progress" error message.
+	ErrGCRunning = errors.New("garbage collection is already in progress")
+	// ErrGCNoReport is "garbage collection was not run yet" error message.
+	ErrGCNoReport = errors.New("garbage collection was not run yet")
+	// ErrNoList is "node does not support chunks listing" error message.
+	ErrNoList = errors.New("node does not support chunks listing")
+)
+
+// GCNodeReport is result of garbage collection at one node.
+type GCNodeReport struct {
+	Idx     int         `json:"idx" yaml:"idx" xml:"idx"`
+	Addr    string      `json:"addr" yaml:"addr" xml:"addr"`
+	Chunks  int         `json:"chunks" yaml:"chunks" xml:"chunks"`                              // number of chunks at node
+	Fresh   int         `json:"fresh" yaml:"fresh" xml:"fresh"`                                 // chunks skipped by grace period
+	Orphans []*pb.Range `json:"orphans,omitempty" yaml:"orphans,omitempty" xml:"orphans>range"` // chunks without file
+	Size    int64       `json:"size" yaml:"size" xml:"size"`                                    // size of orphaned chunks
+	Removed int         `json:"removed" yaml:"removed" xml:"removed"`                           // number of removed orphans
+	Error   string      `json:"error,omitempty" yaml:"error,omitempty" xml:"error,omitempty"`
+}
+
+// GCReport is result of garbage collection at all nodes.
+type GCReport struct {
+	XMLName xml.Name `json:"-" yaml:"-" xml:"gc"`
+
+	DryRun  bool           `json:"dry_run" yaml:"dry_run" xml:"dry_run"`
+	Start   unix_t         `json:"start" yaml:"start" xml:"start"`
+	Finish  unix_t         `json:"finish" yaml:"finish" xml:"finish"`
+	Orphans int            `json:"orphans" yaml:"orphans" xml:"orphans"`
+	Size    int64          `json:"size" yaml:"size" xml:"size"`
+	Removed int            `json:"removed" yaml:"removed" xml:"removed"`
+	Nodes   []GCNodeReport `json:"nodes" yaml:"nodes" xml:"nodes>node"`
+}
+
+// uploads is set of IDs of files which are uploading now.
+// Chunks of such files are not referenced by metadata yet.
+var uploads sync.Map
+
+// IsOrphan returns true if there is no file that refers to chunk
+// with given range at node with given index. Chunk is referred
+// by ID of file and offset of chunk in file.
+func (s *Storage) IsOrphan(idx int, rng *pb.Range) bool {
+	// check uploads before metadata, uploaded file is added to metadata
+	// before it's removed from uploads
+	if _, ok := uploads.Load(rng.FileId); ok {
+		return false
+	}
+	var data, ok = s.FIMap.Load(rng.FileId)
+	if !ok {
+		return true
+	}
+	for _, chunk := range data.(*FileInfo).Chunks {
+		if chunk.NodeId == int64(idx) && chunk.From == rng.From {
+			return false
+		}
+	}
+	return true
+}
+
+// CollectNode finds chunks at node that are not referenced by files
+// metadata, and removes them if it's not dry run.
+func (s *Storage) CollectNode(idx int, node *NodeInfo, dry bool) (nr GCNodeReport) {
+	nr.Idx, nr.Addr = idx, node.Addr
+	if !node.Available() {
+		nr.Error = ErrNodeUnavailable.Error()
+		return
+	}
+	if !node.HasFeature(pb.FeatureList) {
+		nr.Error = ErrNoList.Error()
+		return
+	}
+
+	var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+	defer cancel()
+	var list, err = node.Client.List(ctx, &emptypb.Empty{})
+	if err != nil {
+		nr.Error = err.Error()
+		return
+	}
+	nr.Chunks = len(list.Chunks)
+	var grace = time.Now().Add(-cfg.GCGrace).UnixNano()
+	for _, ci := range list.Chunks {
+		if ci.Modtime > grace {
+			nr.Fresh++
+			continue
+		}
+		if !s.IsOrphan(idx, ci.Range) {
+			continue
+		}
+		nr.Orphans = append(nr.Orphans, ci.Range)
+		nr.Size += ci.Range.To - ci.Range.From
+		if dry {
+			continue
+		}
+		var ctx, cancel = context.WithTimeout(exitctx, cfg.ApiTimeout)
+		if _, err = node.Client.Remove(ctx, ci.Range.ChunkID()); err != nil {
+			nr.Error = err.Error()
+		} else {
+			nr.Removed++
+		}
+		cancel()
+	}
+	return
+}
+
+// CollectGarbage removes orphaned chunks at all nodes, or only finds them
+// at dry run. Returns ErrGCRunning if other collection is in progress.
+func (f *Front) CollectGarbage(dry bool) (rep *GCReport, err error) {
+	if !f.gcrunning.CompareAndSwap(false, true) {
+		err = ErrGCRunning
+		return
+	}
+	defer f.gcrunning.Store(false)
+
+	rep = &GCReport{
+		DryRun: dry,
+		Start:  UnixJSNow(),
+	}
+	f.Storage.nodmux.RLock()
+	var nodes = f.Storage.Nodes
+	f.Storage.nodmux.RUnlock()
+	for idx, node := range nodes {
+		var nr = f.Storage.CollectNode(idx, node, dry)
+		if nr.Error != "" {
+			slog.Warn("garbage collection at node", "addr", node.Addr, "error", nr.Error)
+		}
+		rep.Orphans += len(nr.Orphans)
+		rep.Size += nr.Size
+		rep.Removed += nr.Removed
+		rep.Nodes = append(rep.Nodes, nr)
+	}
+	rep.Finish = UnixJSNow()
+	f.gcreport.Store(rep)
+	slog.Info("garbage collection complete", "dry-run", dry,
+		"orphans", rep.Orphans, "size", rep.Size, "removed", rep.Removed)
+	return
+}
+
+// RunGC starts periodic garbage collection if period is given.
+// Garbage is collected only by leader if fronts cluster is on,
+// because uploads are performed by leader.
+func (f *Front) RunGC() {
+	if cfg.GCPeriod == 0 {
+		return
+	}
+
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		var ticker = time.NewTicker(cfg.GCPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case <-ticker.C:
+				if f.Replica != nil && !f.Replica.IsLeader() {
+					continue
+				}
+				if _, err := f.CollectGarbage(cfg.GCDryRun); err != nil {
+					slog.Warn("garbage collection skipped", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// gcAPI returns report of last garbage collection.
+func (f *Front) gcAPI(w http.ResponseWriter, r *http.Request) {
+	var rep = f.gcreport.Load()
+	if rep == nil {
+		WriteError(w, r, http.StatusNotFound, ErrGCNoReport, AECgcnone)
+		return
+	}
+	WriteOK(w, r, rep)
+}
+
+// gcrunAPI performs garbage collection and returns its report.
+// Optional "dry" argument points to only find orphaned chunks.
+func (f *Front) gcrunAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var dry = cfg.GCDryRun
+	if s := r.FormValue("dry"); len(s) > 0 {
+		if dry, err = strconv.ParseBool(s); err != nil {
+			WriteError400(w, r, err, AECgcbaddry)
+			return
+		}
+	}
+
+	var rep *GCReport
+	if rep, err = f.CollectGarbage(dry); err != nil {
+		WriteError(w, r, http.StatusConflict, err, AECgcbusy)
+		return
+	}
+	WriteOK(w, r, rep)
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/schwarzlichtbezirk/dfs/pb"
-	"google.golang.org/grpc/grpclog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -55,6 +59,108 @@ const (
 	// addnode
 	AECaddnodenodata
 	AECaddnodehas
+
+	// authentication
+	AECauthnone
+	AECauthbadkey
+	AECauthbadjwt
+	AECforbidden
+
+	// file access
+	AECdownloadaccess
+	AECfileinfoaccess
+	AECremoveaccess
+
+	// acl
+	AECaclnoarg
+	AECaclabsent
+	AECaclaccess
+	AECaclchange
+
+	// presign
+	AECauthbadsig
+	AECpresignttl
+	AECpresignnoarg
+	AECpresignabsent
+	AECpresignaccess
+	AECpresignrange
+	AECpresignmethod
+	AECuploadsize
+	AECdownloadgrant
+
+	// buckets
+	AECuploadbucket
+	AECuploadquota
+	AECbucketcreatename
+	AECbucketcreatehas
+	AECbucketdeletenoarg
+	AECbucketdeleteabsent
+	AECbucketdeletehas
+	AEClistabsent
+	AECpresignbucket
+
+	// nodes availability
+	AECuploadnodes
+	AECremovenode
+	AECclearnode
+
+	// fronts cluster
+	AECnotleader
+	AECnoleader
+	AECbadleader
+	AECforward
+	AECuploadapply
+	AECremoveapply
+	AECaclapply
+	AECclearapply
+	AECaddnodeapply
+	AECbucketcreateapply
+	AECbucketdeleteapply
+
+	// garbage collection
+	AECgcnone
+	AECgcbaddry
+	AECgcbusy
+
+	// consistency check
+	AECuploadsum
+	AECfsckbadrepair
+	AECfsckbadverify
+	AECfsckbusy
+	AECfsckapply
+
+	// staged upload
+	AECuploadcommit
+
+	// storage tiers
+	AECuploadclass
+	AEClcbusy
+
+	// versioning
+	AECdownloadbadver
+	AECrestorenoarg
+	AECrestoreabsent
+	AECrestoreaccess
+	AECrestoreapply
+	AECprunenoarg
+	AECpruneabsent
+	AECpruneaccess
+	AECpruneapply
+	AECrestorequota
+	AECrestorecopy
+
+	// node adding
+	AECaddnodetimeout
+
+	// versions access
+	AECuploadaccess
+	AECuploadversion
+
+	// buckets access
+	AECuploadbktaccess
+	AEClistaccess
+	AECpresignbktaccess
+	AECbucketdeleteaccess
 )
 
 // HTTP error messages
@@ -64,9 +170,12 @@ var (
 	ErrArgUndef = errors.New("request content type is undefined")
 	ErrBadEnc   = errors.New("encoding format does not supported")
 
-	ErrNotFound = errors.New("404 file not found")
-	ErrArgBadID = errors.New("file ID can not be parsed as an integer")
-	ErrNodeHas  = errors.New("node with given addres already present")
+	ErrNotFound  = errors.New("404 file not found")
+	ErrArgBadID  = errors.New("file ID can not be parsed as an integer")
+	ErrArgBadVer = errors.New("file version can not be parsed as an integer")
+	ErrNodeHas   = errors.New("node with given addres already present")
+	ErrNodeWait  = errors.New("node is added, but not connected during startup timeout")
+	ErrNoAccess  = errors.New("access to file is denied")
 )
 
 // pingAPI is ping helper to check transactions latency and webserver health.
@@ -78,25 +187,30 @@ func pingAPI(w http.ResponseWriter, r *http.Request) {
 }
 
 // nodesizeAPI returns array with sum size of all chunks on each nodes.
-func nodesizeAPI(w http.ResponseWriter, r *http.Request) {
+func (f *Front) nodesizeAPI(w http.ResponseWriter, r *http.Request) {
 	var ret struct {
 		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
 
 		List []int64 `json:"list" yaml:"list" xml:"list>size"`
 	}
 
-	storage.nodmux.RLock()
-	ret.List = make([]int64, len(storage.Nodes))
-	for i, node := range storage.Nodes {
+	f.Storage.nodmux.RLock()
+	ret.List = make([]int64, len(f.Storage.Nodes))
+	for i, node := range f.Storage.Nodes {
 		ret.List[i] = node.SumSize
 	}
-	storage.nodmux.RUnlock()
+	f.Storage.nodmux.RUnlock()
 
 	WriteOK(w, r, &ret)
 }
 
 // uploadAPI uploads some file.
-func uploadAPI(w http.ResponseWriter, r *http.Request) {
+func (f *Front) uploadAPI(w http.ResponseWriter, r *http.Request) {
+	var p = GetPrincipal(r)
+	if p.Grant != nil && p.Grant.MaxSize > 0 {
+		// reserve 64K for multipart headers
+		r.Body = http.MaxBytesReader(w, r.Body, p.Grant.MaxSize+64*1024)
+	}
 	r.ParseMultipartForm(10 << 20)
 
 	var file, handler, err = r.FormFile("datafile")
@@ -105,13 +219,68 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer file.Close()
+	if p.Grant != nil && p.Grant.MaxSize > 0 && handler.Size > p.Grant.MaxSize {
+		WriteError(w, r, http.StatusRequestEntityTooLarge, ErrTooLarge, AECuploadsize)
+		return
+	}
+
+	// checksum of content to verify it by consistency check
+	var h = sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		WriteError500(w, r, err, AECuploadsum)
+		return
+	}
+
+	var bucket = BucketName(r.FormValue("bucket"))
+	if p.Grant != nil {
+		bucket = BucketName(p.Grant.Bucket)
+	} else if bkt, ok := f.Storage.GetBucket(bucket); ok && !bkt.CanUse(p) {
+		// access to bucket of signed URL was checked when URL was signed
+		WriteError(w, r, http.StatusForbidden, ErrBucketAccess, AECuploadbktaccess)
+		return
+	}
+	// file is distributed only between nodes available at this moment
+	var ids = f.Storage.AvailableNodes()
+	// file of storage class is placed only at nodes of its tier
+	var class = r.FormValue("class")
+	if p.Grant != nil {
+		class = p.Grant.Class
+	}
+	if class != "" {
+		if ids = f.Storage.TierNodes(ids, class); len(ids) == 0 {
+			WriteError(w, r, http.StatusServiceUnavailable, ErrNoTier, AECuploadclass)
+			return
+		}
+	}
+	var nn = int64(len(ids)) // nodes number
+	if nn == 0 {
+		WriteError(w, r, http.StatusServiceUnavailable, ErrNoNodes, AECuploadnodes)
+		return
+	}
+
+	// new version of existing file can be uploaded only by its writers
+	if !f.Storage.CanAddVersion(p, bucket, handler.Filename) {
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECuploadaccess)
+		return
+	}
+
+	if err = f.Storage.Reserve(bucket, handler.Size); err != nil {
+		if errors.Is(err, ErrBucketAbsent) {
+			WriteError(w, r, http.StatusNotFound, err, AECuploadbucket)
+		} else {
+			WriteError(w, r, http.StatusInsufficientStorage, err, AECuploadquota)
+		}
+		return
+	}
 
-	var info = storage.MakeFileInfo(handler)
-	grpclog.Infof("upload file: %s, size: %d, mime: %s\n", handler.Filename, handler.Size, info.MIME)
+	var info = f.Storage.MakeFileInfo(handler, bucket, p.Name)
+	info.Checksum = hex.EncodeToString(h.Sum(nil))
+	info.Class = class
+	// chunks of file are not collected as garbage during upload
+	uploads.Store(info.FileID, struct{}{})
+	defer uploads.Delete(info.FileID)
+	slog.InfoContext(r.Context(), "upload file", "name", handler.Filename, "bucket", bucket, "size", handler.Size, "mime", info.MIME)
 
-	storage.nodmux.RLock()
-	var nn = int64(len(storage.Nodes)) // nodes number
-	storage.nodmux.RUnlock()
 	var cn int64 // chunks number
 	var cr int64 // chunks remainder
 	if cfg.MinNodeChunkSize == 0 {
@@ -123,11 +292,16 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 			cn++
 		}
 	}
+	// nodes for striping, they can keep several chunks of file
+	var sids []int64
+	if cfg.StripeSize > 0 {
+		sids = f.Storage.FeatureNodes(ids, pb.FeatureChunks)
+	}
 	if cn <= nn {
 		info.Chunks = make([]*pb.Range, cn)
 		for i := int64(0); i < cn; i++ {
 			info.Chunks[i] = &pb.Range{
-				NodeId: i,
+				NodeId: ids[i],
 				FileId: info.FileID,
 				From:   cfg.MinNodeChunkSize * i,
 				To:     cfg.MinNodeChunkSize * (i + 1),
@@ -138,15 +312,30 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 			var last = info.Chunks[cn-1]
 			last.To = last.From + cr
 		}
+	} else if len(sids) > 0 {
+		// stripe units are placed round-robin across nodes,
+		// first node is shifted for each file
+		var su = max(cfg.StripeSize, cfg.MinNodeChunkSize) // stripe unit
+		var sn = (handler.Size + su - 1) / su              // stripes number
+		var ns = int64(len(sids))
+		info.Chunks = make([]*pb.Range, sn)
+		for i := int64(0); i < sn; i++ {
+			info.Chunks[i] = &pb.Range{
+				NodeId: sids[(info.FileID+i)%ns],
+				FileId: info.FileID,
+				From:   su * i,
+				To:     min(su*(i+1), handler.Size),
+			}
+		}
 	} else if cfg.NodeFluidFill && nn > 1 {
 		var sizes = make([]int64, nn)
 		var volume int64
-		storage.nodmux.RLock()
+		f.Storage.nodmux.RLock()
 		for i := int64(0); i < nn; i++ {
-			sizes[i] = storage.Nodes[i].SumSize
+			sizes[i] = f.Storage.Nodes[ids[i]].SumSize
 			volume += sizes[i]
 		}
-		storage.nodmux.RUnlock()
+		f.Storage.nodmux.RUnlock()
 
 		// calculate fluid chunk sizes
 		var fsum int64
@@ -160,7 +349,7 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 			var portion = (1 - percent) / float64(nn-1)
 			sizes[i] = int64(float64(handler.Size) * portion)
 			fsum += sizes[i]
-			grpclog.Infof("node#%d, portion=%f, size=%d", i, portion, sizes[i])
+			slog.DebugContext(r.Context(), "fluid chunk", "node", ids[i], "portion", portion, "size", sizes[i])
 		}
 		// store remainder to first node
 		if fsum < handler.Size {
@@ -174,7 +363,7 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 		info.Chunks = make([]*pb.Range, nn)
 		for i := int64(0); i < nn; i++ {
 			info.Chunks[i] = &pb.Range{
-				NodeId: i,
+				NodeId: ids[i],
 				FileId: info.FileID,
 				From:   pos,
 				To:     pos + sizes[i],
@@ -186,7 +375,7 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 		var cs = handler.Size / nn // chunk size
 		for i := int64(0); i < nn; i++ {
 			info.Chunks[i] = &pb.Range{
-				NodeId: i,
+				NodeId: ids[i],
 				FileId: info.FileID,
 				From:   cs * i,
 				To:     cs * (i + 1),
@@ -214,13 +403,28 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 			//defer wg.Done()
 
 			var err error
-			var ctx = context.Background() // no any limits
+			f.Storage.nodmux.RLock()
+			var node = f.Storage.Nodes[rng.NodeId]
+			f.Storage.nodmux.RUnlock()
+			// chunk is hidden at node until commit if node supports it
+			var staged = node.HasFeature(pb.FeatureCommit)
+			// no any limits, but keep the trace
+			var ctx, span = tracer.Start(context.WithoutCancel(r.Context()), "upload chunk",
+				trace.WithAttributes(
+					attribute.Int64("dfs.node.id", rng.NodeId),
+					attribute.String("dfs.node.addr", node.Addr),
+					attribute.Int64("dfs.file.id", rng.FileId),
+					attribute.Int64("dfs.range.from", rng.From),
+					attribute.Int64("dfs.range.to", rng.To),
+				))
+			var fail = func(err error, code int) {
+				errs[i] = MakeAjaxErr(err, code)
+				SpanError(span, err)
+				span.End()
+			}
 			var stream pb.DataGuide_WriteClient
-			storage.nodmux.RLock()
-			var node = storage.Nodes[rng.NodeId]
-			storage.nodmux.RUnlock()
 			if stream, err = node.Client.Write(ctx); err != nil {
-				errs[i] = MakeAjaxErr(err, AECuploadwrite)
+				fail(err, AECuploadwrite)
 				return
 			}
 			var cs = rng.To - rng.From
@@ -235,7 +439,7 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 				//fmux.Unlock()
 
 				if err != nil {
-					errs[i] = MakeAjaxErr(err, AECuploadbuf1)
+					fail(err, AECuploadbuf1)
 					return
 				}
 				var chunk = pb.Chunk{
@@ -245,10 +449,11 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 						From:   rng.From + j*cfg.StreamChunkSize,
 						To:     rng.From + (j+1)*cfg.StreamChunkSize,
 					},
-					Value: buf,
+					Value:  buf,
+					Staged: staged,
 				}
 				if err := stream.Send(&chunk); err != nil {
-					errs[i] = MakeAjaxErr(err, AECuploadsend1)
+					fail(err, AECuploadsend1)
 					return
 				}
 			}
@@ -261,7 +466,7 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 				//fmux.Unlock()
 
 				if err != nil {
-					errs[i] = MakeAjaxErr(err, AECuploadbuf2)
+					fail(err, AECuploadbuf2)
 					return
 				}
 				var chunk = pb.Chunk{
@@ -271,19 +476,21 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 						From:   rng.From + cn*cfg.StreamChunkSize,
 						To:     rng.From + cn*cfg.StreamChunkSize + cr,
 					},
-					Value: buf,
+					Value:  buf,
+					Staged: staged,
 				}
 				if err := stream.Send(&chunk); err != nil {
-					errs[i] = MakeAjaxErr(err, AECuploadsend2)
+					fail(err, AECuploadsend2)
 					return
 				}
 			}
 			var reply *pb.Summary
 			if reply, err = stream.CloseAndRecv(); err != nil {
-				errs[i] = MakeAjaxErr(err, AECuploadreply)
+				fail(err, AECuploadreply)
 				return
 			}
-			grpclog.Infof("chunk %d, size %d, time %v", i, cs, time.Duration(reply.ElapsedTime))
+			slog.InfoContext(ctx, "chunk uploaded", "chunk", i, "node", node.Addr, "size", cs, "elapsed", time.Duration(reply.ElapsedTime))
+			span.End()
 			//}()
 		}
 	}()
@@ -292,30 +499,49 @@ func uploadAPI(w http.ResponseWriter, r *http.Request) {
 	// check for error at any thread
 	for _, err := range errs {
 		if err != nil {
-			// try to remove all stored chunks to prevent garbage accumulation
-			for _, rng := range info.Chunks {
-				ctx, cancel := context.WithTimeout(context.Background(), cfg.ApiTimeout)
-				defer cancel()
-				storage.nodmux.RLock()
-				var node = storage.Nodes[rng.NodeId]
-				storage.nodmux.RUnlock()
-				// do not get a new error, it's already failed state
-				node.Client.Remove(ctx, &pb.FileID{Id: rng.FileId})
-			}
+			f.Storage.RemoveChunks(info.Chunks)
+			f.Storage.Unreserve(bucket, handler.Size)
 			// write error 500
 			WriteRet(w, r, http.StatusInternalServerError, err)
 			return
 		}
 	}
 
-	// save file information at last to get ready for full access after it
-	storage.AddFileInfo(info)
+	// all chunks are acknowledged, make them visible
+	if err = f.Storage.CommitChunks(context.WithoutCancel(r.Context()), info.Chunks); err != nil {
+		f.Storage.RemoveChunks(info.Chunks)
+		f.Storage.Unreserve(bucket, handler.Size)
+		WriteError500(w, r, err, AECuploadcommit)
+		return
+	}
+
+	// check again, other writer could upload file with the same name
+	if !f.Storage.CanAddVersion(p, bucket, handler.Filename) {
+		f.Storage.RemoveChunks(info.Chunks)
+		f.Storage.Unreserve(bucket, handler.Size)
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECuploadversion)
+		return
+	}
+
+	// save file information at last to get ready for full access after it,
+	// file gets next version number of file with its name
+	var res any
+	res, err = f.ApplyCmd(&Command{Op: OpAddFile, File: info})
+	f.Storage.Unreserve(bucket, handler.Size)
+	if err != nil {
+		f.Storage.RemoveChunks(info.Chunks)
+		WriteError(w, r, http.StatusServiceUnavailable, err, AECuploadapply)
+		return
+	}
+	info = res.(*FileInfo)
+	mtrUploaded.Add(float64(info.Size))
 
 	WriteOK(w, r, info)
 }
 
-func downloadAPI(w http.ResponseWriter, r *http.Request) {
+func (f *Front) downloadAPI(w http.ResponseWriter, r *http.Request) {
 	var err error
+	var p = GetPrincipal(r)
 
 	// get arguments
 	var fid int64
@@ -329,6 +555,14 @@ func downloadAPI(w http.ResponseWriter, r *http.Request) {
 	if s := r.FormValue("name"); len(s) > 0 {
 		name = s
 	}
+	var bucket = r.FormValue("bucket")
+	var version int
+	if s := r.FormValue("version"); len(s) > 0 {
+		if version, err = strconv.Atoi(s); err != nil {
+			WriteError400(w, r, ErrArgBadVer, AECdownloadbadver)
+			return
+		}
+	}
 
 	if fid == 0 && name == "" {
 		WriteError400(w, r, ErrNoData, AECdownloadnoarg)
@@ -336,22 +570,40 @@ func downloadAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var info *FileInfo
-	if info = storage.FindFileInfo(fid, name); info == nil {
+	if info = f.Storage.FindVersion(bucket, fid, name, version); info == nil {
 		WriteError(w, r, http.StatusNotFound, ErrNotFound, AECdownloadabsent)
 		return
 	}
+	if p.Grant != nil {
+		// access was checked when URL was signed
+		if p.Grant.FileID != info.FileID {
+			WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECdownloadgrant)
+			return
+		}
+	} else if !info.CanRead(p) {
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECdownloadaccess)
+		return
+	}
 
+	var reader = f.Storage.NewReader(r.Context(), info)
+	var content io.ReadSeeker = reader
+	if p.Grant != nil && p.Grant.To != 0 {
+		content = io.NewSectionReader(reader, p.Grant.From, p.Grant.To-p.Grant.From)
+	}
 	w.Header().Set("Content-Type", info.MIME)
-	http.ServeContent(w, r, info.Name, time.Time{}, storage.NewReader(info))
+	http.ServeContent(w, r, info.Name, time.Time{}, content)
 }
 
-func fileinfoAPI(w http.ResponseWriter, r *http.Request) {
+func (f *Front) fileinfoAPI(w http.ResponseWriter, r *http.Request) {
 	var err error
 	var arg struct {
 		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
 
-		Name string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
-		ID   int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		Bucket   string `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+		Name     string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+		ID       int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		Version  int    `json:"version,omitempty" yaml:"version,omitempty" xml:"version,omitempty"`
+		Versions bool   `json:"versions,omitempty" yaml:"versions,omitempty" xml:"versions,omitempty"` // list all versions
 	}
 	var ret *FileInfo
 
@@ -364,20 +616,44 @@ func fileinfoAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ret = storage.FindFileInfo(arg.ID, arg.Name)
+	var p = GetPrincipal(r)
+	ret = f.Storage.FindVersion(arg.Bucket, arg.ID, arg.Name, arg.Version)
+	if ret != nil && !ret.CanRead(p) {
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECfileinfoaccess)
+		return
+	}
+
+	// list versions of file from oldest to latest
+	if arg.Versions && ret != nil {
+		var list struct {
+			XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+			List []*FileInfo `json:"list" yaml:"list" xml:"list>fi"`
+		}
+		list.List = []*FileInfo{}
+		for _, fi := range f.Storage.Versions(ret.Bucket, ret.Name) {
+			if fi.CanRead(p) {
+				list.List = append(list.List, fi)
+			}
+		}
+		WriteOK(w, r, &list)
+		return
+	}
 
 	WriteOK(w, r, ret)
 }
 
-// removeAPI deletes all chunks of pointed file from nodes.
-// returns file info of removed file.
-func removeAPI(w http.ResponseWriter, r *http.Request) {
+// removeAPI deletes pointed file with all its versions, and all chunks
+// of them from nodes. Returns file info of latest removed version.
+func (f *Front) removeAPI(w http.ResponseWriter, r *http.Request) {
 	var err error
+	var code int
 	var arg struct {
 		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
 
-		Name string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
-		ID   int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		Bucket string `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+		Name   string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+		ID     int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
 	}
 	var ret *FileInfo
 
@@ -390,66 +666,170 @@ func removeAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ret = storage.FindFileInfo(arg.ID, arg.Name); ret == nil {
+	if ret = f.Storage.FindFileInfo(arg.Bucket, arg.ID, arg.Name); ret == nil {
 		WriteError(w, r, http.StatusNotFound, ErrNotFound, AECremoveabsent)
 		return
 	}
+	// name of versioned file must not resolve to previous
+	// version after remove, so all versions are removed
+	var list = f.Storage.Versions(ret.Bucket, ret.Name)
+	var p = GetPrincipal(r)
+	for _, fi := range list {
+		if !fi.CanWrite(p) {
+			WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECremoveaccess)
+			return
+		}
+	}
+	ret = list[len(list)-1]
 
-	storage.DelFileInfo(ret) // file data can not be accessed after it
+	// file data can not be accessed after it
+	var chunks []*pb.Range
+	for _, fi := range list {
+		if _, err = f.ApplyCmd(&Command{Op: OpDelFile, FileID: fi.FileID}); err != nil {
+			WriteError(w, r, http.StatusServiceUnavailable, err, AECremoveapply)
+			return
+		}
+		chunks = append(chunks, fi.Chunks...)
+	}
 	// try to remove all chunks
-	for _, rng := range ret.Chunks {
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.ApiTimeout)
-		defer cancel()
-		storage.nodmux.RLock()
-		var node = storage.Nodes[rng.NodeId]
-		storage.nodmux.RUnlock()
-		if _, err1 := node.Client.Remove(ctx, &pb.FileID{Id: rng.FileId}); err1 != nil {
-			err = err1 // save error for future break
+	for _, rng := range chunks {
+		f.Storage.nodmux.RLock()
+		var node = f.Storage.Nodes[rng.NodeId]
+		f.Storage.nodmux.RUnlock()
+		if !node.Available() {
+			code, err = AECremovenode, ErrNodeUnavailable
+			continue
+		}
+		var ctx, cancel = context.WithTimeout(context.Background(), cfg.ApiTimeout)
+		var _, err1 = node.Client.Remove(ctx, rng.ChunkID())
+		cancel()
+		if err1 != nil {
+			code, err = AECremovegrpc, err1 // save error for future break
 		}
 	}
 	if err != nil {
-		WriteError500(w, r, err, AECremovegrpc)
+		WriteError500(w, r, err, code)
 		return
 	}
 
 	WriteOK(w, r, ret)
 }
 
+// aclAPI returns owner and ACL of pointed file, and changes them if new values are given.
+func (f *Front) aclAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Bucket string  `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+		Name   string  `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+		ID     int64   `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		Owner  *string `json:"owner,omitempty" yaml:"owner,omitempty" xml:"owner,omitempty"`
+		ACL    *ACL    `json:"acl,omitempty" yaml:"acl,omitempty" xml:"acl,omitempty"`
+	}
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		FileID int64  `json:"file_id" yaml:"file_id" xml:"file_id"`
+		Owner  string `json:"owner" yaml:"owner" xml:"owner"`
+		ACL    ACL    `json:"acl" yaml:"acl" xml:"acl"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	if arg.ID == 0 && arg.Name == "" {
+		WriteError400(w, r, ErrNoData, AECaclnoarg)
+		return
+	}
+
+	var info *FileInfo
+	if info = f.Storage.FindFileInfo(arg.Bucket, arg.ID, arg.Name); info == nil {
+		WriteError(w, r, http.StatusNotFound, ErrNotFound, AECaclabsent)
+		return
+	}
+	var p = GetPrincipal(r)
+	if !info.CanRead(p) {
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECaclaccess)
+		return
+	}
+
+	// change owner and ACL if it's given
+	if arg.Owner != nil || arg.ACL != nil {
+		if !info.CanChangeACL(p) {
+			WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECaclchange)
+			return
+		}
+		var owner, acl = info.Owner, info.ACL
+		if arg.Owner != nil {
+			owner = *arg.Owner
+		}
+		if arg.ACL != nil {
+			acl = *arg.ACL
+		}
+		var res any
+		if res, err = f.ApplyCmd(&Command{Op: OpSetACL, FileID: info.FileID, Owner: owner, ACL: acl}); err != nil {
+			WriteError(w, r, http.StatusServiceUnavailable, err, AECaclapply)
+			return
+		}
+		if info = res.(*FileInfo); info == nil {
+			WriteError(w, r, http.StatusNotFound, ErrNotFound, AECaclabsent)
+			return
+		}
+	}
+
+	ret.FileID, ret.Owner, ret.ACL = info.FileID, info.Owner, info.ACL
+	WriteOK(w, r, &ret)
+}
+
 // clearAPI deletes all data at storage, purge nodes, and sets files ID counter to 0.
-func clearAPI(w http.ResponseWriter, r *http.Request) {
+func (f *Front) clearAPI(w http.ResponseWriter, r *http.Request) {
 	var err error
+	var code int
 
-	storage.Clear()
+	if _, err = f.ApplyCmd(&Command{Op: OpClear}); err != nil {
+		WriteError(w, r, http.StatusServiceUnavailable, err, AECclearapply)
+		return
+	}
 
 	// Purge all nodes in locked state.
 	// So users can not add some files or do anything during this operation.
 	func() {
 		// Unlock put to defer, if panic will be caused
-		storage.nodmux.Lock()
-		defer storage.nodmux.Unlock()
+		f.Storage.nodmux.Lock()
+		defer f.Storage.nodmux.Unlock()
 
 		// Try to purge all nodes
-		for _, node := range storage.Nodes {
-			ctx, cancel := context.WithTimeout(context.Background(), cfg.ApiTimeout)
-			defer cancel()
-			if _, err1 := node.Client.Purge(ctx, &emptypb.Empty{}); err1 != nil {
-				err = err1 // save error for future break
+		for _, node := range f.Storage.Nodes {
+			if !node.Available() {
+				code, err = AECclearnode, ErrNodeUnavailable
+				continue
+			}
+			var ctx, cancel = context.WithTimeout(context.Background(), cfg.ApiTimeout)
+			var _, err1 = node.Client.Purge(ctx, &emptypb.Empty{})
+			cancel()
+			if err1 != nil {
+				code, err = AECcleargrpc, err1 // save error for future break
 			}
 		}
 	}()
 
 	if err != nil {
-		WriteError500(w, r, err, AECcleargrpc)
+		WriteError500(w, r, err, code)
 		return
 	}
 
-	grpclog.Infoln("content is cleared")
+	slog.InfoContext(r.Context(), "content is cleared")
 
 	WriteOK(w, r, nil)
 }
 
-// addnodeAPI adds new node to composition in runtime and waits until connection will be established.
-func addnodeAPI(w http.ResponseWriter, r *http.Request) {
+// addnodeAPI adds new node to composition in runtime and waits until connection
+// will be established, but not longer than startup timeout. Node that is not
+// connected during this time stays in composition, and connection to it
+// is continued in background.
+func (f *Front) addnodeAPI(w http.ResponseWriter, r *http.Request) {
 	var err error
 	var arg struct {
 		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
@@ -471,32 +851,28 @@ func addnodeAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var found bool
-	storage.nodmux.RLock()
-	for _, node := range storage.Nodes {
-		if node.Addr == arg.Addr {
-			found = true
-			break
-		}
+	var res any
+	if res, err = f.ApplyCmd(&Command{Op: OpAddNode, Addr: arg.Addr}); err != nil {
+		WriteError(w, r, http.StatusServiceUnavailable, err, AECaddnodeapply)
+		return
 	}
-	storage.nodmux.RUnlock()
-	if found {
+	var added = res.(NodeAdded)
+	if added.Has {
 		WriteError400(w, r, ErrNodeHas, AECaddnodehas)
 		return
 	}
-
-	var node = &NodeInfo{
-		Addr:    arg.Addr,
-		SumSize: 0,
+	ret.Idx = added.Idx
+
+	var timer = time.NewTimer(cfg.StartupTimeout)
+	defer timer.Stop()
+	select {
+	case <-added.Node.connected:
+	case <-timer.C:
+		WriteError(w, r, http.StatusGatewayTimeout, ErrNodeWait, AECaddnodetimeout)
+		return
+	case <-r.Context().Done():
+		return
 	}
 
-	storage.nodmux.Lock()
-	ret.Idx = len(storage.Nodes) // get size, it will be index
-	storage.Nodes = append(storage.Nodes, node)
-	storage.nodmux.Unlock()
-
-	node.RunGRPC()
-	grpcwg.Wait()
-
 	WriteOK(w, r, &ret)
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+)
+
+var (
+	// ErrLCRunning is "lifecycle rules are already applying" error message.
+	ErrLCRunning = errors.New("lifecycle rules are already applying")
+	// ErrBadRule is "lifecycle rule must be in form 'from:to:age'" error message.
+	ErrBadRule = errors.New("lifecycle rule must be in form 'from:to:age'")
+	// ErrFileGone is "file was deleted during the move" error message.
+	ErrFileGone = errors.New("file was deleted during the move")
+)
+
+// LifecycleRule moves files of storage class `From` to class `To`
+// when they are older than `Age`.
+type LifecycleRule struct {
+	From string
+	To   string
+	Age  time.Duration
+}
+
+// ParseRule parses lifecycle rule in form 'from:to:age', like 'hot:cold:720h'.
+func ParseRule(s string) (rule LifecycleRule, err error) {
+	var parts = strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] == parts[1] {
+		err = ErrBadRule
+		return
+	}
+	rule.From, rule.To = parts[0], parts[1]
+	rule.Age, err = time.ParseDuration(parts[2])
+	return
+}
+
+// LifecycleMove is result of moving of one file to other storage class.
+type LifecycleMove struct {
+	FileID int64  `json:"file_id" yaml:"file_id" xml:"file_id"`
+	Bucket string `json:"bucket" yaml:"bucket" xml:"bucket"`
+	Name   string `json:"name" yaml:"name" xml:"name"`
+	From   string `json:"from" yaml:"from" xml:"from"`
+	To     string `json:"to" yaml:"to" xml:"to"`
+	Size   int64  `json:"size" yaml:"size" xml:"size"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty" xml:"error,omitempty"`
+}
+
+// LifecycleReport is result of lifecycle rules applying.
+type LifecycleReport struct {
+	XMLName xml.Name `json:"-" yaml:"-" xml:"lifecycle"`
+
+	Start  unix_t          `json:"start" yaml:"start" xml:"start"`
+	Finish unix_t          `json:"finish" yaml:"finish" xml:"finish"`
+	Moved  int             `json:"moved" yaml:"moved" xml:"moved"`
+	Size   int64           `json:"size" yaml:"size" xml:"size"` // size of moved files
+	Failed int             `json:"failed" yaml:"failed" xml:"failed"`
+	Files  []LifecycleMove `json:"files,omitempty" yaml:"files,omitempty" xml:"files>file"`
+}
+
+var (
+	// lcrules is parsed lifecycle rules.
+	lcrules []LifecycleRule
+	// lcrunning is set while lifecycle rules are applying.
+	lcrunning atomic.Bool
+)
+
+// SetChunks replaces storage class and chunks of file with given ID,
+// and updates statistics of nodes. File information is immutable,
+// so it's replaced by modified copy. Returns the copy, or nil
+// if file is not found.
+func (s *Storage) SetChunks(fid int64, class string, chunks []*pb.Range) *FileInfo {
+	for {
+		var data, ok = s.FIMap.Load(fid)
+		if !ok {
+			return nil
+		}
+		var old = data.(*FileInfo)
+		var info = *old // make copy
+		info.Class, info.Chunks = class, chunks
+		if s.FIMap.CompareAndSwap(fid, data, &info) {
+			// update statistics
+			s.nodmux.Lock()
+			for _, rng := range old.Chunks {
+				s.Nodes[rng.NodeId].NumChunks--
+				s.Nodes[rng.NodeId].SumSize -= rng.To - rng.From
+			}
+			for _, rng := range chunks {
+				s.Nodes[rng.NodeId].NumChunks++
+				s.Nodes[rng.NodeId].SumSize += rng.To - rng.From
+			}
+			s.nodmux.Unlock()
+			return &info
+		}
+	}
+}
+
+// MoveFile copies chunks of file to nodes of tier with label of given
+// storage class, then switches file information to new chunks, and
+// removes old chunks. Chunks placed at nodes of this tier already
+// are left in place.
+func (f *Front) MoveFile(fi *FileInfo, class string) (err error) {
+	var s = f.Storage
+	var ids = s.TierNodes(s.AvailableNodes(), class)
+	if len(fi.Chunks) > 1 {
+		ids = s.FeatureNodes(ids, pb.FeatureChunks)
+	}
+	if len(ids) == 0 {
+		return ErrNoTier
+	}
+
+	var chunks = make([]*pb.Range, len(fi.Chunks)) // chunks after the move
+	var copied, moved []*pb.Range                  // new copies, and chunks to remove
+	for i, rng := range fi.Chunks {
+		s.nodmux.RLock()
+		var src = s.Nodes[rng.NodeId]
+		s.nodmux.RUnlock()
+		if src.Tier() == class {
+			chunks[i] = rng
+			continue
+		}
+		var dstid = ids[(fi.FileID+int64(i))%int64(len(ids))]
+		chunks[i] = &pb.Range{
+			NodeId: dstid,
+			FileId: rng.FileId,
+			From:   rng.From,
+			To:     rng.To,
+		}
+		moved = append(moved, rng)
+		if rng.To == rng.From {
+			continue // empty chunks are not written
+		}
+		if !src.Available() {
+			err = ErrNodeUnavailable
+			break
+		}
+		s.nodmux.RLock()
+		var dst = s.Nodes[dstid]
+		s.nodmux.RUnlock()
+		if err = CopyChunk(exitctx, src, dst, rng, chunks[i]); err != nil {
+			break
+		}
+		copied = append(copied, chunks[i])
+	}
+	if err != nil {
+		s.RemoveChunks(copied)
+		return
+	}
+
+	var res any
+	if res, err = f.ApplyCmd(&Command{Op: OpSetChunks, FileID: fi.FileID, Class: class, Chunks: chunks}); err != nil {
+		s.RemoveChunks(copied)
+		return
+	}
+	if res.(*FileInfo) == nil {
+		s.RemoveChunks(copied)
+		err = ErrFileGone
+		return
+	}
+	s.RemoveChunks(moved)
+	return
+}
+
+// ApplyLifecycle moves files to other storage classes by lifecycle rules.
+// File is moved by first matching rule. Files without upload time are
+// not moved. Returns ErrLCRunning if rules are applying by other call.
+func (f *Front) ApplyLifecycle() (rep *LifecycleReport, err error) {
+	if !lcrunning.CompareAndSwap(false, true) {
+		err = ErrLCRunning
+		return
+	}
+	defer lcrunning.Store(false)
+
+	rep = &LifecycleReport{
+		Start: UnixJSNow(),
+	}
+	var files []*FileInfo
+	f.Storage.FIMap.Range(func(key, value any) bool {
+		files = append(files, value.(*FileInfo))
+		return true
+	})
+	var now = time.Now()
+	for _, fi := range files {
+		if fi.Created == 0 {
+			continue
+		}
+		for _, rule := range lcrules {
+			if fi.Class != rule.From || now.Sub(fi.Created.Time()) < rule.Age {
+				continue
+			}
+			var mv = LifecycleMove{
+				FileID: fi.FileID,
+				Bucket: fi.Bucket,
+				Name:   fi.Name,
+				From:   rule.From,
+				To:     rule.To,
+				Size:   fi.Size,
+			}
+			if err := f.MoveFile(fi, rule.To); err != nil {
+				slog.Warn("file is not moved to storage class", "file_id", fi.FileID, "class", rule.To, "error", err)
+				mv.Error = err.Error()
+				rep.Failed++
+			} else {
+				rep.Moved++
+				rep.Size += fi.Size
+			}
+			rep.Files = append(rep.Files, mv)
+			break
+		}
+	}
+	rep.Finish = UnixJSNow()
+	slog.Info("lifecycle rules applied", "moved", rep.Moved, "size", rep.Size, "failed", rep.Failed)
+	return
+}
+
+// RunLifecycle parses lifecycle rules, and starts periodic applying
+// of them if period is given. Rules are applied only by leader if
+// fronts cluster is on, because leader makes metadata changes.
+func (f *Front) RunLifecycle() {
+	for _, s := range cfg.LifecycleRules {
+		var rule, err = ParseRule(s)
+		if err != nil {
+			telemetry.Fatal("can not parse lifecycle rule", "rule", s, "error", err)
+		}
+		lcrules = append(lcrules, rule)
+	}
+	if cfg.LifecyclePeriod == 0 || len(lcrules) == 0 {
+		return
+	}
+
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		var ticker = time.NewTicker(cfg.LifecyclePeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case <-ticker.C:
+				if f.Replica != nil && !f.Replica.IsLeader() {
+					continue
+				}
+				if _, err := f.ApplyLifecycle(); err != nil {
+					slog.Warn("lifecycle rules applying skipped", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// lifecycleAPI applies lifecycle rules and returns report about moved files.
+func (f *Front) lifecycleAPI(w http.ResponseWriter, r *http.Request) {
+	var rep, err = f.ApplyLifecycle()
+	if err != nil {
+		WriteError(w, r, http.StatusConflict, err, AEClcbusy)
+		return
+	}
+	WriteOK(w, r, rep)
+}
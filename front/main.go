@@ -2,8 +2,9 @@ package main
 
 func main() {
 	Init()
+	var f = NewFront()
 	var gmux = NewRouter()
-	RegisterRoutes(gmux)
-	Run(gmux)
+	f.RegisterRoutes(gmux)
+	f.Run(gmux)
 	Done()
 }
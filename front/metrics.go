@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics of front service, exposed in Prometheus text format at "/metrics".
+var (
+	mtrRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "dfs",
+		Subsystem: "front",
+		Name:      "http_requests_total",
+		Help:      "Number of processed HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	mtrLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dfs",
+		Subsystem: "front",
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	mtrUploaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "dfs",
+		Subsystem: "front",
+		Name:      "uploaded_bytes_total",
+		Help:      "Total size of uploaded files.",
+	})
+	mtrDownloaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "dfs",
+		Subsystem: "front",
+		Name:      "downloaded_bytes_total",
+		Help:      "Total size of content read from nodes.",
+	})
+	mtrGRPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "dfs",
+		Subsystem: "front",
+		Name:      "grpc_errors_total",
+		Help:      "Number of failed gRPC calls to nodes by node, method and status code.",
+	}, []string{"node", "method", "code"})
+)
+
+var (
+	descNodeSize = prometheus.NewDesc("dfs_front_node_size_bytes",
+		"Total size of all chunks saved on node.", []string{"node"}, nil)
+	descNodeChunks = prometheus.NewDesc("dfs_front_node_chunks",
+		"Number of chunks saved on node.", []string{"node"}, nil)
+)
+
+// nodesCollector exposes statistics of nodes list of storage, that can be
+// changed at runtime, so statistics are collected at each scrape.
+type nodesCollector struct {
+	s *Storage
+}
+
+// Describe is prometheus.Collector interface implementation.
+func (c nodesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descNodeSize
+	ch <- descNodeChunks
+}
+
+// Collect is prometheus.Collector interface implementation.
+func (c nodesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.s.nodmux.RLock()
+	defer c.s.nodmux.RUnlock()
+	for _, node := range c.s.Nodes {
+		if node == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(descNodeSize, prometheus.GaugeValue, float64(node.SumSize), node.Addr)
+		ch <- prometheus.MustNewConstMetric(descNodeChunks, prometheus.GaugeValue, float64(node.NumChunks), node.Addr)
+	}
+}
+
+// RegisterNodesMetrics exposes statistics of nodes of given storage.
+// Statistics are exposed only for the first registered storage,
+// if several fronts are run in one process.
+func RegisterNodesMetrics(s *Storage) {
+	var err = prometheus.Register(nodesCollector{s})
+	if _, ok := err.(prometheus.AlreadyRegisteredError); err != nil && !ok {
+		panic(err)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(mtrRequests, mtrLatency, mtrUploaded, mtrDownloaded, mtrGRPCErrors)
+}
+
+// statusWriter is http.ResponseWriter that remembers status code of response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader is http.ResponseWriter interface implementation.
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write is http.ResponseWriter interface implementation.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns original writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MetricsMiddleware counts requests and measures its latency by route templates.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var route = "unknown"
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if tpl, err := cr.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		var sw = &statusWriter{ResponseWriter: w}
+		var t0 = time.Now()
+		defer func() {
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+			mtrLatency.WithLabelValues(route, r.Method).Observe(time.Since(t0).Seconds())
+			mtrRequests.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Inc()
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// fail counts failed call to node.
+func (node *NodeInfo) fail(method string, err error) {
+	node.fails.Add(1)
+	mtrGRPCErrors.WithLabelValues(node.Addr, method, status.Code(err).String()).Inc()
+}
+
+// unaryErrors is client interceptor that counts calls to node and failed calls.
+func (node *NodeInfo) unaryErrors(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	node.calls.Add(1)
+	var err = invoker(ctx, method, req, reply, cc, opts...)
+	if err != nil {
+		node.fail(method, err)
+	}
+	return err
+}
+
+// streamErrors is client interceptor that counts streams to node and failed streams.
+func (node *NodeInfo) streamErrors(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	node.calls.Add(1)
+	var cs, err = streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		node.fail(method, err)
+		return nil, err
+	}
+	return &countedStream{ClientStream: cs, node: node, method: method}, nil
+}
+
+// countedStream is client stream that counts its failure.
+type countedStream struct {
+	grpc.ClientStream
+	node   *NodeInfo
+	method string
+}
+
+// RecvMsg is grpc.ClientStream interface implementation.
+func (s *countedStream) RecvMsg(m any) error {
+	var err = s.ClientStream.RecvMsg(m)
+	if err != nil && err != io.EOF {
+		s.node.fail(s.method, err)
+	}
+	return err
+}
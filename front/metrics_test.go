@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSeveralFronts(t *testing.T) {
+	var f1, f2 = NewFront(), NewFront()
+	// second front in the same process must not panic
+	RegisterNodesMetrics(f1.Storage)
+	RegisterNodesMetrics(f2.Storage)
+
+	// running maintenance at one front does not lock out other front
+	f1.gcrunning.Store(true)
+	f1.fsckrunning.Store(true)
+	if _, err := f1.CollectGarbage(true); !errors.Is(err, ErrGCRunning) {
+		t.Errorf("expected error %v, got %v", ErrGCRunning, err)
+	}
+	if _, err := f2.CollectGarbage(true); err != nil {
+		t.Errorf("garbage collection at other front: %v", err)
+	}
+	if _, err := f1.CheckConsistency(false, false); !errors.Is(err, ErrFsckRunning) {
+		t.Errorf("expected error %v, got %v", ErrFsckRunning, err)
+	}
+	if _, err := f2.CheckConsistency(false, false); err != nil {
+		t.Errorf("consistency check at other front: %v", err)
+	}
+	if f1.gcreport.Load() != nil || f2.gcreport.Load() == nil {
+		t.Errorf("report of garbage collection is not kept by its front")
+	}
+}
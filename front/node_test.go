@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// testNode is stand-in of storage node for tests of front,
+// it keeps chunks in memory and serves them by gRPC.
+type testNode struct {
+	pb.UnimplementedDataGuideServer
+	Addr string
+
+	mux    sync.Mutex
+	chunks map[[2]int64]*pb.Chunk // by file ID and chunk start
+}
+
+// StartTestNode starts node at free local port with given server options.
+// Node is stopped on test cleanup.
+func StartTestNode(t *testing.T, opts ...grpc.ServerOption) *testNode {
+	t.Helper()
+	var lis, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var node = &testNode{
+		Addr:   lis.Addr().String(),
+		chunks: map[[2]int64]*pb.Chunk{},
+	}
+	var server = grpc.NewServer(opts...)
+	pb.RegisterDataGuideServer(server, node)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return node
+}
+
+// Len returns number of chunks stored at node.
+func (n *testNode) Len() int {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	return len(n.chunks)
+}
+
+// find returns chunk of file that contains given offset.
+func (n *testNode) find(fid, off int64) *pb.Chunk {
+	for key, chunk := range n.chunks {
+		if key[0] == fid && chunk.Range.From <= off && off < chunk.Range.To {
+			return chunk
+		}
+	}
+	return nil
+}
+
+func (n *testNode) Read(ctx context.Context, arg *pb.Range) (*pb.Chunk, error) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	var chunk = n.find(arg.FileId, arg.From)
+	if chunk == nil || arg.To > chunk.Range.To {
+		return &pb.Chunk{}, nil
+	}
+	return &pb.Chunk{
+		Range: arg,
+		Value: chunk.Value[arg.From-chunk.Range.From : arg.To-chunk.Range.From],
+	}, nil
+}
+
+func (n *testNode) Write(stream pb.DataGuide_WriteServer) error {
+	var count int32
+	for {
+		var piece, err = stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&pb.Summary{ChunkCount: count})
+		}
+		if err != nil {
+			return err
+		}
+		count++
+		n.mux.Lock()
+		// next piece is glued to chunk which it continues
+		if chunk := n.find(piece.Range.FileId, piece.Range.From-1); chunk != nil && chunk.Range.To == piece.Range.From {
+			chunk.Value = append(chunk.Value, piece.Value...)
+			chunk.Range.To += int64(len(piece.Value))
+		} else {
+			n.chunks[[2]int64{piece.Range.FileId, piece.Range.From}] = &pb.Chunk{
+				Range: &pb.Range{
+					NodeId: piece.Range.NodeId,
+					FileId: piece.Range.FileId,
+					From:   piece.Range.From,
+					To:     piece.Range.From + int64(len(piece.Value)),
+				},
+				Value: append([]byte(nil), piece.Value...),
+			}
+		}
+		n.mux.Unlock()
+	}
+}
+
+func (n *testNode) GetRange(ctx context.Context, arg *pb.FileID) (*pb.Range, error) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	var res = &pb.Range{}
+	for key, chunk := range n.chunks {
+		if key[0] == arg.Id && (arg.From == nil || key[1] == *arg.From) {
+			if res.To == 0 {
+				res.NodeId, res.FileId = chunk.Range.NodeId, chunk.Range.FileId
+				res.From, res.To = chunk.Range.From, chunk.Range.To
+			} else {
+				res.From, res.To = min(res.From, chunk.Range.From), max(res.To, chunk.Range.To)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (n *testNode) Remove(ctx context.Context, arg *pb.FileID) (*pb.Range, error) {
+	var res, _ = n.GetRange(ctx, arg)
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	for key := range n.chunks {
+		if key[0] == arg.Id && (arg.From == nil || key[1] == *arg.From) {
+			delete(n.chunks, key)
+		}
+	}
+	return res, nil
+}
+
+func (n *testNode) Purge(ctx context.Context, arg *emptypb.Empty) (*emptypb.Empty, error) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	clear(n.chunks)
+	return &emptypb.Empty{}, nil
+}
+
+func (n *testNode) Info(ctx context.Context, arg *emptypb.Empty) (*pb.NodeInfo, error) {
+	return &pb.NodeInfo{
+		Id:       n.Addr,
+		Protocol: pb.Protocol,
+		Features: []string{pb.FeatureHealth, pb.FeatureList, pb.FeatureChunks},
+	}, nil
+}
+
+func (n *testNode) List(ctx context.Context, arg *emptypb.Empty) (*pb.ChunkList, error) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	var res = &pb.ChunkList{}
+	for _, chunk := range n.chunks {
+		res.Chunks = append(res.Chunks, &pb.ChunkInfo{Range: chunk.Range})
+	}
+	return res, nil
+}
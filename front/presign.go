@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthPresigned is authentication method for presigned URLs.
+const AuthPresigned = "presigned"
+
+// Presigned URLs errors
+var (
+	ErrSigBad     = errors.New("URL signature is not valid")
+	ErrSigExpired = errors.New("URL signature is expired")
+	ErrSigMethod  = errors.New("HTTP method is not allowed for signed URL")
+	ErrSigArgs    = errors.New("signed URL arguments can not be parsed")
+	ErrBadMethod  = errors.New("only GET for download or POST, PUT for upload can be signed")
+	ErrBadTTL     = errors.New("expiry duration is out of allowed range")
+	ErrBadRange   = errors.New("byte range is out of file bounds")
+	ErrTooLarge   = errors.New("file size exceeds allowed by signed URL")
+)
+
+// Grant is constraints of presigned URL.
+type Grant struct {
+	Method  string    // allowed HTTP method
+	Path    string    // signed URL path
+	FileID  int64     // file to download, zero for upload
+	Bucket  string    // bucket to upload
+	Expires time.Time // expiry time
+	From    int64     // start of byte range to download
+	To      int64     // end of byte range to download, zero for whole file
+	MaxSize int64     // maximum size of file to upload, zero for unlimited
+	Class   string    // storage class of file to upload
+	By      string    // name of principal who signed URL
+}
+
+// payload returns canonical representation of grant to sign.
+func (g *Grant) payload() []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%d\n%s\n%d\n%d-%d\n%d\n%s\n%s",
+		g.Method, g.Path, g.FileID, g.Bucket, g.Expires.Unix(), g.From, g.To, g.MaxSize, g.Class, g.By))
+}
+
+// Sign returns signature of grant made by given secret.
+func (g *Grant) Sign(secret []byte) string {
+	var mac = hmac.New(sha256.New, secret)
+	mac.Write(g.payload())
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Query returns URL query arguments with signed grant.
+func (g *Grant) Query(secret []byte) url.Values {
+	var q = url.Values{}
+	q.Set("m", g.Method)
+	if g.FileID != 0 {
+		q.Set("id", strconv.FormatInt(g.FileID, 10))
+	}
+	if g.Bucket != "" {
+		q.Set("bkt", g.Bucket)
+	}
+	q.Set("exp", strconv.FormatInt(g.Expires.Unix(), 10))
+	if g.To != 0 {
+		q.Set("rng", fmt.Sprintf("%d-%d", g.From, g.To))
+	}
+	if g.MaxSize != 0 {
+		q.Set("max", strconv.FormatInt(g.MaxSize, 10))
+	}
+	if g.Class != "" {
+		q.Set("cls", g.Class)
+	}
+	q.Set("by", g.By)
+	q.Set("sig", g.Sign(secret))
+	return q
+}
+
+// ParseGrant extracts grant from URL of request.
+func ParseGrant(r *http.Request) (g *Grant, sig string, err error) {
+	var q = r.URL.Query()
+	g = &Grant{
+		Method: q.Get("m"),
+		Path:   r.URL.Path,
+		Bucket: q.Get("bkt"),
+		Class:  q.Get("cls"),
+		By:     q.Get("by"),
+	}
+	if s := q.Get("id"); s != "" {
+		if g.FileID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, "", ErrSigArgs
+		}
+	}
+	var exp int64
+	if exp, err = strconv.ParseInt(q.Get("exp"), 10, 64); err != nil {
+		return nil, "", ErrSigArgs
+	}
+	g.Expires = time.Unix(exp, 0)
+	if s := q.Get("rng"); s != "" {
+		var from, to, _ = strings.Cut(s, "-")
+		if g.From, err = strconv.ParseInt(from, 10, 64); err != nil {
+			return nil, "", ErrSigArgs
+		}
+		if g.To, err = strconv.ParseInt(to, 10, 64); err != nil {
+			return nil, "", ErrSigArgs
+		}
+	}
+	if s := q.Get("max"); s != "" {
+		if g.MaxSize, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, "", ErrSigArgs
+		}
+	}
+	sig = q.Get("sig")
+	return
+}
+
+// CheckPresigned verifies presigned URL of request
+// and returns principal with grant of this URL.
+func (a *Authenticator) CheckPresigned(r *http.Request) (*Principal, error) {
+	var g, sig, err = ParseGrant(r)
+	if err != nil {
+		return nil, err
+	}
+	a.mux.RLock()
+	var secret = a.pskey
+	a.mux.RUnlock()
+	if !hmac.Equal([]byte(sig), []byte(g.Sign(secret))) {
+		return nil, ErrSigBad
+	}
+	if time.Now().After(g.Expires) {
+		return nil, ErrSigExpired
+	}
+	if r.Method != g.Method && !(r.Method == http.MethodHead && g.Method == http.MethodGet) {
+		return nil, ErrSigMethod
+	}
+	var role = RoleReader
+	if g.Method != http.MethodGet {
+		role = RoleWriter
+	}
+	return &Principal{
+		Name:   g.By,
+		Method: AuthPresigned,
+		Role:   role,
+		Grant:  g,
+	}, nil
+}
+
+// presignAPI makes signed URL to download or upload file without other credentials.
+func (f *Front) presignAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Method  string `json:"method" yaml:"method" xml:"method"`
+		Bucket  string `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+		Name    string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+		ID      int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		TTL     int64  `json:"ttl" yaml:"ttl" xml:"ttl"` // in seconds
+		From    int64  `json:"from,omitempty" yaml:"from,omitempty" xml:"from,omitempty"`
+		To      int64  `json:"to,omitempty" yaml:"to,omitempty" xml:"to,omitempty"`
+		MaxSize int64  `json:"max_size,omitempty" yaml:"max_size,omitempty" xml:"max_size,omitempty"`
+		Class   string `json:"class,omitempty" yaml:"class,omitempty" xml:"class,omitempty"`
+	}
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		URL     string `json:"url" yaml:"url" xml:"url"`
+		Expires unix_t `json:"expires" yaml:"expires" xml:"expires"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	var ttl = time.Duration(arg.TTL) * time.Second
+	if ttl <= 0 || ttl > cfg.PresignMaxTTL {
+		WriteError400(w, r, ErrBadTTL, AECpresignttl)
+		return
+	}
+
+	var p = GetPrincipal(r)
+	var g = &Grant{
+		Method:  strings.ToUpper(arg.Method),
+		Expires: time.Now().Add(ttl),
+		By:      p.Name,
+	}
+	switch g.Method {
+	case http.MethodGet:
+		if arg.ID == 0 && arg.Name == "" {
+			WriteError400(w, r, ErrNoData, AECpresignnoarg)
+			return
+		}
+		var info *FileInfo
+		if info = f.Storage.FindFileInfo(arg.Bucket, arg.ID, arg.Name); info == nil {
+			WriteError(w, r, http.StatusNotFound, ErrNotFound, AECpresignabsent)
+			return
+		}
+		if !info.CanRead(p) {
+			WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECpresignaccess)
+			return
+		}
+		if bkt, ok := f.Storage.GetBucket(info.Bucket); ok && !bkt.CanUse(p) {
+			WriteError(w, r, http.StatusForbidden, ErrBucketAccess, AECpresignbktaccess)
+			return
+		}
+		if arg.From < 0 || arg.To > info.Size || (arg.To != 0 && arg.From >= arg.To) {
+			WriteError400(w, r, ErrBadRange, AECpresignrange)
+			return
+		}
+		g.Path = "/api/download"
+		g.FileID = info.FileID
+		g.From, g.To = arg.From, arg.To
+	case http.MethodPost, http.MethodPut:
+		if p.Role < RoleWriter {
+			WriteError(w, r, http.StatusForbidden, ErrForbidden, AECpresignaccess)
+			return
+		}
+		if bkt, ok := f.Storage.GetBucket(BucketName(arg.Bucket)); !ok {
+			WriteError(w, r, http.StatusNotFound, ErrBucketAbsent, AECpresignbucket)
+			return
+		} else if !bkt.CanUse(p) {
+			WriteError(w, r, http.StatusForbidden, ErrBucketAccess, AECpresignbktaccess)
+			return
+		}
+		g.Path = "/api/upload"
+		g.Bucket = BucketName(arg.Bucket)
+		g.MaxSize = arg.MaxSize
+		g.Class = arg.Class
+	default:
+		WriteError400(w, r, ErrBadMethod, AECpresignmethod)
+		return
+	}
+
+	auth.mux.RLock()
+	var secret = auth.pskey
+	auth.mux.RUnlock()
+	var scheme = "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	var u = url.URL{
+		Scheme:   scheme,
+		Host:     r.Host,
+		Path:     g.Path,
+		RawQuery: g.Query(secret).Encode(),
+	}
+	ret.URL = u.String()
+	ret.Expires = UnixJS(g.Expires)
+
+	WriteOK(w, r, &ret)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckPresigned(t *testing.T) {
+	var a = &Authenticator{pskey: []byte("presign secret")}
+	var download = &Grant{
+		Method:  http.MethodGet,
+		Path:    "/api/download",
+		FileID:  5,
+		Expires: time.Now().Add(time.Hour),
+		From:    0,
+		To:      1024,
+		By:      "alice",
+	}
+	var upload = &Grant{
+		Method:  http.MethodPost,
+		Path:    "/api/upload",
+		Bucket:  "team1",
+		Expires: time.Now().Add(time.Hour),
+		MaxSize: 1 << 20,
+		Class:   "cold",
+		By:      "alice",
+	}
+	var expired = *download
+	expired.Expires = time.Now().Add(-time.Second)
+
+	// target makes URL of signed grant, and changes its arguments
+	var target = func(g *Grant, path string, set map[string]string) string {
+		var q = g.Query(a.pskey)
+		for k, v := range set {
+			if v == "" {
+				q.Del(k)
+			} else {
+				q.Set(k, v)
+			}
+		}
+		if path == "" {
+			path = g.Path
+		}
+		return path + "?" + q.Encode()
+	}
+
+	var tests = []struct {
+		what   string
+		method string
+		target string
+		role   Role
+		err    error
+	}{
+		{"download", "GET", target(download, "", nil), RoleReader, nil},
+		{"head of download", "HEAD", target(download, "", nil), RoleReader, nil},
+		{"upload", "POST", target(upload, "", nil), RoleWriter, nil},
+		{"bad signature", "GET", target(download, "", map[string]string{"sig": "AAAA"}), 0, ErrSigBad},
+		{"no signature", "GET", target(download, "", map[string]string{"sig": ""}), 0, ErrSigBad},
+		{"signed by other secret", "GET", target(download, "", map[string]string{
+			"sig": download.Sign([]byte("other secret"))}), 0, ErrSigBad},
+		{"expired", "GET", target(&expired, "", nil), 0, ErrSigExpired},
+		{"prolonged", "GET", target(&expired, "", map[string]string{
+			"exp": download.Query(nil).Get("exp")}), 0, ErrSigBad},
+		{"upload by download URL", "POST", target(download, "", nil), 0, ErrSigMethod},
+		{"download by upload URL", "GET", target(upload, "", nil), 0, ErrSigMethod},
+		{"tampered method", "POST", target(download, "", map[string]string{"m": "POST"}), 0, ErrSigBad},
+		{"tampered path", "GET", target(download, "/api/fileinfo", nil), 0, ErrSigBad},
+		{"tampered file", "GET", target(download, "", map[string]string{"id": "6"}), 0, ErrSigBad},
+		{"tampered range", "GET", target(download, "", map[string]string{"rng": "0-4096"}), 0, ErrSigBad},
+		{"removed range", "GET", target(download, "", map[string]string{"rng": ""}), 0, ErrSigBad},
+		{"tampered signer", "GET", target(download, "", map[string]string{"by": "root"}), 0, ErrSigBad},
+		{"tampered bucket", "POST", target(upload, "", map[string]string{"bkt": "team2"}), 0, ErrSigBad},
+		{"tampered size", "POST", target(upload, "", map[string]string{"max": "0"}), 0, ErrSigBad},
+		{"tampered class", "POST", target(upload, "", map[string]string{"cls": "hot"}), 0, ErrSigBad},
+		{"bad file", "GET", target(download, "", map[string]string{"id": "x"}), 0, ErrSigArgs},
+		{"bad expiry", "GET", target(download, "", map[string]string{"exp": ""}), 0, ErrSigArgs},
+		{"bad range", "GET", target(download, "", map[string]string{"rng": "0-x"}), 0, ErrSigArgs},
+	}
+	for _, test := range tests {
+		var p, err = a.CheckPresigned(httptest.NewRequest(test.method, test.target, nil))
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.what, test.err, err)
+			continue
+		}
+		if err == nil && (p.Role != test.role || p.Name != "alice" || p.Method != AuthPresigned) {
+			t.Errorf("%s: got principal %s with role %s by %s", test.what, p.Name, p.Role, p.Method)
+		}
+	}
+}
+
+func TestPresignClass(t *testing.T) {
+	StartExit(t)
+	var _, server = StartTestFront(t, 1)
+	var presign = func(arg string) string {
+		var ret struct {
+			URL string `json:"url"`
+		}
+		if status := CallAPI(t, server.URL+"/api/presign", "", arg, &ret); status != http.StatusOK {
+			t.Fatalf("presign status %d", status)
+		}
+		return ret.URL
+	}
+
+	// class of signed URL can not be changed by upload form
+	var plain = presign(`{"method":"POST","ttl":60}`)
+	if status, info := UploadFile(t, plain+"&class=cold", "", "a.txt", []byte("data")); status != http.StatusOK || info.Class != "" {
+		t.Errorf("upload by URL without class has status %d and class %q", status, info.Class)
+	}
+	// test nodes have no tiers, so upload of signed class is refused
+	var cold = presign(`{"method":"POST","ttl":60,"class":"cold"}`)
+	if status, _ := UploadFile(t, cold, "", "b.txt", []byte("data")); status != http.StatusServiceUnavailable {
+		t.Errorf("upload by URL with class has status %d, expected %d", status, http.StatusServiceUnavailable)
+	}
+	// class can not be removed from signed URL
+	var u, _ = url.Parse(cold)
+	var q = u.Query()
+	q.Del("cls")
+	u.RawQuery = q.Encode()
+	if status, _ := UploadFile(t, u.String(), "", "c.txt", []byte("data")); status != http.StatusUnauthorized {
+		t.Errorf("upload by URL without signed class has status %d, expected %d", status, http.StatusUnauthorized)
+	}
+}
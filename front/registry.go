@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrNotRegistered is "node is not registered" error message.
+	ErrNotRegistered = errors.New("node is not registered")
+	// ErrRegNoAuth is "registry requires token or mutual TLS with CA" error message.
+	ErrRegNoAuth = errors.New("registry requires token or mutual TLS with CA")
+	// ErrRegPlain is "registry token requires TLS" error message.
+	ErrRegPlain = errors.New("registry token requires TLS")
+	// ErrRegToken is "registry token is invalid" error message.
+	ErrRegToken = errors.New("registry token is invalid")
+	// ErrRegEmpty is "registry token is empty" error message.
+	ErrRegEmpty = errors.New("registry token is empty")
+)
+
+// registryServer is Registry gRPC service, nodes register at it on startup
+// and then confirm that they are alive by heartbeats.
+type registryServer struct {
+	pb.UnimplementedRegistryServer
+	f *Front
+}
+
+func (s *registryServer) Register(ctx context.Context, arg *pb.Announce) (res *pb.Registration, err error) {
+	if arg.Addr == "" {
+		err = status.Error(codes.InvalidArgument, ErrNoData.Error())
+		return
+	}
+	var ret any
+	if ret, err = s.f.ApplyCmd(&Command{Op: OpAddNode, Addr: arg.Addr}); err != nil {
+		err = status.Error(codes.Unavailable, err.Error())
+		return
+	}
+	var added = ret.(NodeAdded)
+	if added.Has {
+		slog.InfoContext(ctx, "node registered again", "idx", added.Idx, "addr", arg.Addr, "id", arg.Id)
+	} else {
+		slog.InfoContext(ctx, "node joined", "idx", added.Idx, "addr", arg.Addr, "id", arg.Id)
+	}
+	added.Node.Beat()
+	res = &pb.Registration{
+		NodeId: int64(added.Idx),
+		Period: int64(cfg.HeartbeatPeriod),
+	}
+	return
+}
+
+func (s *registryServer) Heartbeat(ctx context.Context, arg *pb.Announce) (res *pb.Registration, err error) {
+	var idx, node = s.f.Storage.FindNode(arg.Addr)
+	if node == nil {
+		err = status.Error(codes.NotFound, ErrNotRegistered.Error())
+		return
+	}
+	node.Beat()
+	res = &pb.Registration{
+		NodeId: int64(idx),
+		Period: int64(cfg.HeartbeatPeriod),
+	}
+	return
+}
+
+// Beat marks node as alive at registry.
+func (node *NodeInfo) Beat() {
+	node.hbmux.Lock()
+	node.regbeat = time.Now()
+	node.hbmux.Unlock()
+	if node.departed.Swap(false) {
+		slog.Info("departed node is back", "addr", node.Addr)
+	}
+}
+
+// CheckDeparted marks registered nodes that do not send heartbeats
+// longer than depart timeout as departed.
+func (f *Front) CheckDeparted() {
+	var now = time.Now()
+	f.Storage.nodmux.RLock()
+	defer f.Storage.nodmux.RUnlock()
+	for _, node := range f.Storage.Nodes {
+		node.hbmux.RLock()
+		var regbeat = node.regbeat
+		node.hbmux.RUnlock()
+		if regbeat.IsZero() || now.Sub(regbeat) < cfg.DepartTimeout {
+			continue
+		}
+		if !node.departed.Swap(true) {
+			slog.Warn("node departed", "addr", node.Addr, "last_beat", regbeat)
+		}
+	}
+}
+
+// UnaryServerToken returns interceptor that checks up shared token
+// presented by node in gRPC metadata. All calls pass if token is nil.
+func UnaryServerToken(token []byte) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if token == nil {
+			return handler(ctx, req)
+		}
+		var md, _ = metadata.FromIncomingContext(ctx)
+		var vals = md.Get(pb.RegistryTokenKey)
+		if len(vals) == 0 || subtle.ConstantTimeCompare([]byte(vals[0]), token) != 1 {
+			return nil, status.Error(codes.Unauthenticated, ErrRegToken.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ReadRegistryToken reads shared token of registry if token file is given,
+// returns nil token otherwise.
+func ReadRegistryToken() (token []byte, err error) {
+	if cfg.TokenFile == "" {
+		return
+	}
+	var body []byte
+	if body, err = os.ReadFile(CfgPath(cfg.TokenFile)); err != nil {
+		return
+	}
+	if token = []byte(strings.TrimSpace(string(body))); len(token) == 0 {
+		err = ErrRegEmpty
+	}
+	return
+}
+
+// RunRegistry starts gRPC server with Registry service
+// if registry port is given, and checks departed nodes.
+// Registry is served only if nodes are authenticated by shared
+// token over TLS, or by mutual TLS with certificates signed by CA.
+func (f *Front) RunRegistry() {
+	if cfg.RegistryPort == "" {
+		return
+	}
+
+	var token, err = ReadRegistryToken()
+	if err != nil {
+		telemetry.Fatal("can not read registry token", "file", cfg.TokenFile, "error", err)
+	}
+	if token == nil && (nodecerts == nil || cfg.CAFile == "") {
+		telemetry.Fatal("registry can not be served", "addr", cfg.RegistryPort, "error", ErrRegNoAuth)
+	}
+	// token sent by plain connection can be taken by anyone on the path
+	if token != nil && nodecerts == nil {
+		telemetry.Fatal("registry can not be served", "addr", cfg.RegistryPort, "error", ErrRegPlain)
+	}
+
+	var lis net.Listener
+	if lis, err = net.Listen("tcp", cfg.RegistryPort); err != nil {
+		telemetry.Fatal("failed to listen", "addr", cfg.RegistryPort, "error", err)
+	}
+	var options = []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(telemetry.UnaryServerRequestID, UnaryServerToken(token)),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+	}
+	if nodecerts != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(nodecerts.ServerConfig())))
+	}
+	var server = grpc.NewServer(options...)
+	pb.RegisterRegistryServer(server, &registryServer{f: f})
+
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+
+		slog.Info("registry starts", "addr", cfg.RegistryPort)
+		go func() {
+			if err := server.Serve(lis); err != nil {
+				slog.Error("registry serve", "addr", cfg.RegistryPort, "error", err)
+			}
+		}()
+
+		var ticker = time.NewTicker(cfg.HeartbeatPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exitctx.Done():
+				server.GracefulStop()
+				slog.Info("registry closed", "addr", cfg.RegistryPort)
+				return
+			case <-ticker.C:
+				f.CheckDeparted()
+			}
+		}
+	}()
+}
@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Metadata operations.
+const (
+	OpAddFile   = "addfile"
+	OpDelFile   = "delfile"
+	OpSetACL    = "setacl"
+	OpAddNode   = "addnode"
+	OpAddBucket = "addbucket"
+	OpDelBucket = "delbucket"
+	OpClear     = "clear"
+	OpRecount   = "recount"
+	OpSetChunks = "setchunks"
+)
+
+// ForwardHeader is header of request forwarded by follower to leader,
+// it has identity of follower.
+const ForwardHeader = "X-Forwarded-Front"
+
+// Fronts cluster errors
+var (
+	ErrNotLeader = errors.New("front is not a leader of fronts cluster")
+	ErrNoLeader  = errors.New("fronts cluster has no leader")
+	ErrBadOp     = errors.New("unknown metadata operation")
+	ErrBadPeer   = errors.New("peer of fronts cluster must be in form 'url=address:port'")
+	ErrNoRaftDir = errors.New("directory of Raft state is not given")
+	ErrNoPresign = errors.New("secret of presigned URLs must be given by file for fronts cluster")
+)
+
+// Command is metadata change. It's applied to storage directly,
+// or replicated through Raft log if fronts cluster is on.
+type Command struct {
+	Op     string      `json:"op"`
+	File   *FileInfo   `json:"file,omitempty"`
+	FileID int64       `json:"file_id,omitempty"`
+	Owner  string      `json:"owner,omitempty"`
+	ACL    ACL         `json:"acl"`
+	Addr   string      `json:"addr,omitempty"`
+	Bucket *Bucket     `json:"bucket,omitempty"`
+	Name   string      `json:"name,omitempty"`
+	Class  string      `json:"class,omitempty"`
+	Chunks []*pb.Range `json:"chunks,omitempty"`
+}
+
+// NodeAdded is result of node adding command.
+type NodeAdded struct {
+	Idx  int
+	Node *NodeInfo
+	Has  bool // node with such address was present before
+}
+
+// Apply changes storage by given command.
+// Returns result of command, or error.
+func (s *Storage) Apply(cmd *Command) any {
+	switch cmd.Op {
+	case OpAddFile:
+		s.AddVersion(cmd.File)
+		// keep IDs counter ahead of all added files
+		for {
+			var id = atomic.LoadInt64(&s.idconter)
+			if id >= cmd.File.FileID || atomic.CompareAndSwapInt64(&s.idconter, id, cmd.File.FileID) {
+				break
+			}
+		}
+		return cmd.File
+	case OpDelFile:
+		if val, ok := s.FIMap.Load(cmd.FileID); ok {
+			var fi = val.(*FileInfo)
+			s.DelFileInfo(fi)
+			return fi
+		}
+		return (*FileInfo)(nil)
+	case OpSetACL:
+		return s.SetACL(cmd.FileID, cmd.Owner, cmd.ACL)
+	case OpAddNode:
+		var idx, node, has = s.AddNode(cmd.Addr)
+		return NodeAdded{idx, node, has}
+	case OpAddBucket:
+		var bkt, err = s.AddBucket(cmd.Bucket)
+		if err != nil {
+			return err
+		}
+		return bkt
+	case OpDelBucket:
+		var bkt, err = s.DelBucket(cmd.Name)
+		if err != nil {
+			return err
+		}
+		return bkt
+	case OpClear:
+		s.Clear()
+		return nil
+	case OpRecount:
+		s.Recount()
+		return nil
+	case OpSetChunks:
+		return s.SetChunks(cmd.FileID, cmd.Class, cmd.Chunks)
+	}
+	return ErrBadOp
+}
+
+// StorageState is snapshot of storage metadata.
+type StorageState struct {
+	IDCounter int64       `json:"id_counter"`
+	Nodes     []string    `json:"nodes"` // addresses of nodes in order of indexes
+	Buckets   []Bucket    `json:"buckets"`
+	Files     []*FileInfo `json:"files"`
+}
+
+// State returns snapshot of storage metadata.
+func (s *Storage) State() (st *StorageState) {
+	st = &StorageState{
+		IDCounter: atomic.LoadInt64(&s.idconter),
+		Buckets:   s.GetBuckets(),
+	}
+	s.nodmux.RLock()
+	for _, node := range s.Nodes {
+		st.Nodes = append(st.Nodes, node.Addr)
+	}
+	s.nodmux.RUnlock()
+	s.FIMap.Range(func(key, value any) bool {
+		st.Files = append(st.Files, value.(*FileInfo))
+		return true
+	})
+	return
+}
+
+// SetState replaces storage metadata by given snapshot. Nodes list is
+// replaced by nodes of snapshot in the same order, because files refer
+// to nodes by index. Nodes with the same addresses are kept with their
+// connections.
+func (s *Storage) SetState(st *StorageState) {
+	s.nodmux.Lock()
+	var nodes = make([]*NodeInfo, len(st.Nodes))
+	for i, addr := range st.Nodes {
+		if idx := slices.IndexFunc(s.Nodes, func(node *NodeInfo) bool {
+			return node.Addr == addr
+		}); idx >= 0 {
+			nodes[i] = s.Nodes[idx]
+		} else {
+			nodes[i] = NewNodeInfo(addr)
+		}
+	}
+	s.Nodes = nodes
+	s.nodmux.Unlock()
+	s.NotifyNodes()
+
+	s.Clear()
+	s.bktmux.Lock()
+	s.Buckets = make(map[string]*Bucket, len(st.Buckets))
+	for _, bkt := range st.Buckets {
+		var bkt = bkt                 // localize
+		bkt.Bytes, bkt.Objects = 0, 0 // will be accounted with files
+		s.Buckets[bkt.Name] = &bkt
+	}
+	s.bktmux.Unlock()
+	for _, fi := range st.Files {
+		s.AddFileInfo(fi)
+	}
+	atomic.StoreInt64(&s.idconter, st.IDCounter)
+}
+
+// storageFSM is Raft finite state machine that applies commands to storage.
+type storageFSM struct {
+	s *Storage
+}
+
+// Apply is raft.FSM interface implementation.
+func (f *storageFSM) Apply(l *raft.Log) any {
+	var cmd Command
+	if err := json.Unmarshal(l.Data, &cmd); err != nil {
+		return err
+	}
+	return f.s.Apply(&cmd)
+}
+
+// Snapshot is raft.FSM interface implementation.
+func (f *storageFSM) Snapshot() (raft.FSMSnapshot, error) {
+	return f.s.State(), nil
+}
+
+// Restore is raft.FSM interface implementation.
+func (f *storageFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	var st StorageState
+	if err := json.NewDecoder(rc).Decode(&st); err != nil {
+		return err
+	}
+	f.s.SetState(&st)
+	return nil
+}
+
+// Persist is raft.FSMSnapshot interface implementation.
+func (st *StorageState) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(st); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// Release is raft.FSMSnapshot interface implementation.
+func (st *StorageState) Release() {}
+
+// Replica is Raft replica of storage metadata at fronts cluster.
+type Replica struct {
+	ID   string
+	Raft *raft.Raft
+
+	bolt *raftboltdb.BoltStore // nil if Raft state is kept in memory
+}
+
+// raftWriter writes lines of Raft logger to default slog logger
+// with level taken from line prefix.
+type raftWriter struct{}
+
+// Write is io.Writer interface implementation.
+func (raftWriter) Write(p []byte) (int, error) {
+	var msg = strings.TrimSpace(string(p))
+	var lvl = slog.LevelInfo
+	switch {
+	case strings.HasPrefix(msg, "[ERROR]"):
+		lvl = slog.LevelError
+	case strings.HasPrefix(msg, "[WARN]"):
+		lvl = slog.LevelWarn
+	case strings.HasPrefix(msg, "[DEBUG]"), strings.HasPrefix(msg, "[TRACE]"):
+		lvl = slog.LevelDebug
+	}
+	slog.Log(context.Background(), lvl, msg, "system", "raft")
+	return len(p), nil
+}
+
+// RaftLogger returns logger for Raft internals that writes to default slog logger.
+func RaftLogger() hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:        "raft",
+		Level:       hclog.Info,
+		Output:      raftWriter{},
+		DisableTime: true,
+	})
+}
+
+// NewReplica starts Raft replica of given storage with given identity
+// and transport. Raft log, term, vote and snapshots are kept at given
+// directory, so restarted replica continues with its state. State is kept
+// in memory if directory is empty, it's only for tests, because restarted
+// replica would lose its vote. Cluster is bootstrapped with given peers
+// only if replica has no state, peers must include this replica. Several
+// replicas can be run in one process with raft.InmemTransport and own storages.
+func NewReplica(id string, s *Storage, trans raft.Transport, dir string, peers []raft.Server) (r *Replica, err error) {
+	var conf = raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(id)
+	conf.Logger = RaftLogger()
+	r = &Replica{ID: id}
+
+	var logs raft.LogStore
+	var stable raft.StableStore
+	var snaps raft.SnapshotStore
+	if dir == "" {
+		var mem = raft.NewInmemStore()
+		logs, stable, snaps = mem, mem, raft.NewInmemSnapshotStore()
+	} else {
+		if err = os.MkdirAll(dir, 0o700); err != nil {
+			return
+		}
+		if r.bolt, err = raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db")); err != nil {
+			return
+		}
+		defer func() {
+			if err != nil {
+				r.bolt.Close()
+			}
+		}()
+		logs, stable = r.bolt, r.bolt
+		if snaps, err = raft.NewFileSnapshotStoreWithLogger(dir, 2, conf.Logger); err != nil {
+			return
+		}
+	}
+
+	var has bool
+	if has, err = raft.HasExistingState(logs, stable, snaps); err != nil {
+		return
+	}
+	if r.Raft, err = raft.NewRaft(conf, &storageFSM{s}, logs, stable, snaps, trans); err != nil {
+		return
+	}
+	if !has && len(peers) > 0 {
+		if err = r.Raft.BootstrapCluster(raft.Configuration{Servers: peers}).Error(); err != nil {
+			r.Raft.Shutdown()
+		}
+	}
+	return
+}
+
+// Shutdown stops Raft replica and closes its stores.
+func (r *Replica) Shutdown() (err error) {
+	err = r.Raft.Shutdown().Error()
+	if r.bolt != nil {
+		err = errors.Join(err, r.bolt.Close())
+	}
+	return
+}
+
+// Apply appends command to Raft log, and returns result
+// of command applied to storage of this replica.
+func (r *Replica) Apply(cmd *Command) (res any, err error) {
+	var data []byte
+	if data, err = json.Marshal(cmd); err != nil {
+		return
+	}
+	var f = r.Raft.Apply(data, cfg.ApiTimeout)
+	if err = f.Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			err = ErrNotLeader
+		}
+		return
+	}
+	res = f.Response()
+	return
+}
+
+// IsLeader returns true if replica is leader of cluster.
+func (r *Replica) IsLeader() bool {
+	return r.Raft.State() == raft.Leader
+}
+
+// Leader returns identity of current leader, or empty string if there is no leader.
+func (r *Replica) Leader() string {
+	var _, id = r.Raft.LeaderWithID()
+	return string(id)
+}
+
+// WaitLeader waits until cluster will have leader, or timeout expires.
+func (r *Replica) WaitLeader(timeout time.Duration) bool {
+	var deadline = time.Now().Add(timeout)
+	for r.Leader() == "" {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
+
+// ApplyCmd applies command to storage directly, or through Raft log if
+// fronts cluster is on. Returns result of command, or error.
+func (f *Front) ApplyCmd(cmd *Command) (res any, err error) {
+	if f.Replica == nil {
+		res = f.Storage.Apply(cmd)
+	} else if res, err = f.Replica.Apply(cmd); err != nil {
+		return
+	}
+	if e, ok := res.(error); ok {
+		return nil, e
+	}
+	return
+}
+
+// RunReplica starts Raft replica of storage metadata
+// if identity of front in cluster is given.
+func (f *Front) RunReplica() {
+	if cfg.RaftID == "" {
+		return
+	}
+	if cfg.RaftDir == "" {
+		telemetry.Fatal("can not start fronts cluster", "error", ErrNoRaftDir)
+	}
+	// followers forward uploads to leader, so URLs signed
+	// by any front must be valid at the leader
+	if cfg.PresignSecretFile == "" {
+		telemetry.Fatal("can not start fronts cluster", "error", ErrNoPresign)
+	}
+
+	var peers []raft.Server
+	for _, p := range cfg.RaftPeers {
+		var id, addr, ok = strings.Cut(p, "=")
+		if !ok {
+			telemetry.Fatal("can not start fronts cluster", "peer", p, "error", ErrBadPeer)
+		}
+		peers = append(peers, raft.Server{
+			ID:      raft.ServerID(id),
+			Address: raft.ServerAddress(addr),
+		})
+	}
+	var trans, err = raft.NewTCPTransportWithLogger(cfg.RaftAddr, nil, 3, 10*time.Second, RaftLogger())
+	if err != nil {
+		telemetry.Fatal("can not start Raft transport", "addr", cfg.RaftAddr, "error", err)
+	}
+	if f.Replica, err = NewReplica(cfg.RaftID, f.Storage, trans, CfgPath(cfg.RaftDir), peers); err != nil {
+		telemetry.Fatal("can not start fronts cluster", "error", err)
+	}
+	slog.Info("fronts cluster replica starts", "id", cfg.RaftID, "addr", cfg.RaftAddr, "dir", cfg.RaftDir, "peers", len(peers))
+
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		<-exitctx.Done()
+		if err := f.Replica.Shutdown(); err != nil {
+			slog.Error("fronts cluster replica shutdown", "error", err)
+		}
+		trans.Close()
+		slog.Info("fronts cluster replica closed")
+	}()
+}
+
+// RunNodeList adds nodes from given list to storage. If fronts cluster is
+// on, nodes are added by leader through Raft log each time it's elected,
+// and followers receive them from the log. So all fronts have the same
+// nodes in the same order, because files refer to nodes by index.
+func (f *Front) RunNodeList(list []string) {
+	var add = func() {
+		for _, addr := range list {
+			if _, err := f.ApplyCmd(&Command{Op: OpAddNode, Addr: addr}); err != nil {
+				slog.Warn("node from list is not added", "addr", addr, "error", err)
+				return
+			}
+		}
+	}
+	if f.Replica == nil {
+		add()
+		return
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case leader := <-f.Replica.Raft.LeaderCh():
+				if leader {
+					add()
+				}
+			}
+		}
+	}()
+}
+
+// Leader serves request by given handler if this front is leader of fronts
+// cluster or if cluster is off, and forwards request to leader otherwise.
+func (f *Front) Leader(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if f.Replica == nil || f.Replica.IsLeader() {
+			next(w, r)
+			return
+		}
+		if r.Header.Get(ForwardHeader) != "" { // prevent forwarding loop
+			WriteError(w, r, http.StatusServiceUnavailable, ErrNotLeader, AECnotleader)
+			return
+		}
+		var leader = f.Replica.Leader()
+		if leader == "" {
+			WriteError(w, r, http.StatusServiceUnavailable, ErrNoLeader, AECnoleader)
+			return
+		}
+		var target, err = url.Parse(leader)
+		if err != nil {
+			WriteError500(w, r, err, AECbadleader)
+			return
+		}
+
+		var proxy = httputil.NewSingleHostReverseProxy(target)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			WriteError(w, r, http.StatusBadGateway, err, AECforward)
+		}
+		r.Header.Set(ForwardHeader, f.Replica.ID)
+		// leader continues the same request ID and trace
+		r.Header.Set(telemetry.RequestIDHeader, telemetry.RequestID(r.Context()))
+		w.Header().Del(telemetry.RequestIDHeader)
+		otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+		slog.DebugContext(r.Context(), "request is forwarded to leader", "leader", leader)
+		proxy.ServeHTTP(w, r)
+	}
+}
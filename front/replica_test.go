@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// testCluster is fronts cluster run in one process, with Raft replicas
+// connected by in-memory transport and REST API served by test servers.
+type testCluster struct {
+	Fronts  []*Front
+	Servers []*httptest.Server
+}
+
+// StartTestCluster starts fronts cluster with given number of fronts.
+// Each front adds given nodes in own order, as it would be with
+// different configurations.
+func StartTestCluster(t *testing.T, num int, nodes []string) *testCluster {
+	t.Helper()
+	var c = &testCluster{
+		Fronts:  make([]*Front, num),
+		Servers: make([]*httptest.Server, num),
+	}
+	var trans = make([]*raft.InmemTransport, num)
+	var peers = make([]raft.Server, num)
+	for i := range num {
+		var gmux = NewRouter()
+		c.Fronts[i] = NewFront()
+		c.Fronts[i].RegisterRoutes(gmux)
+		// URL of server is identity of front, it's known before start
+		c.Servers[i] = httptest.NewUnstartedServer(gmux)
+		var addr raft.ServerAddress
+		addr, trans[i] = raft.NewInmemTransport("")
+		peers[i] = raft.Server{
+			ID:      raft.ServerID("http://" + c.Servers[i].Listener.Addr().String()),
+			Address: addr,
+		}
+	}
+	for i := range num {
+		for j := range num {
+			if i != j {
+				trans[i].Connect(peers[j].Address, trans[j])
+			}
+		}
+	}
+	for i, f := range c.Fronts {
+		var err error
+		if f.Replica, err = NewReplica(string(peers[i].ID), f.Storage, trans[i], "", peers); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Replica.Shutdown() })
+		c.Servers[i].Start()
+		t.Cleanup(c.Servers[i].Close)
+		f.Storage.RunNodes()
+		var list = slices.Clone(nodes)
+		slices.Reverse(list[:i%len(list)+1])
+		f.RunNodeList(list)
+	}
+	return c
+}
+
+// Followers returns indexes of fronts which are not leaders.
+func (c *testCluster) Followers() (list []int) {
+	for i, f := range c.Fronts {
+		if !f.Replica.IsLeader() {
+			list = append(list, i)
+		}
+	}
+	return
+}
+
+func TestFrontsCluster(t *testing.T) {
+	StartExit(t)
+	var nodes = make([]string, 3)
+	for i := range nodes {
+		nodes[i] = StartTestNode(t).Addr
+	}
+	var c = StartTestCluster(t, 3, nodes)
+
+	WaitFor(t, 10*time.Second, "leader", func() bool {
+		return len(c.Followers()) == len(c.Fronts)-1
+	})
+	// all fronts have the same nodes in the same order
+	WaitFor(t, 10*time.Second, "nodes list", func() bool {
+		var list = NodeAddrs(c.Fronts[0].Storage)
+		if len(list) != len(nodes) {
+			return false
+		}
+		for _, f := range c.Fronts[1:] {
+			if !slices.Equal(NodeAddrs(f.Storage), list) {
+				return false
+			}
+		}
+		return true
+	})
+	WaitFor(t, 10*time.Second, "nodes connection", func() bool {
+		for _, f := range c.Fronts {
+			if len(f.Storage.AvailableNodes()) != len(nodes) {
+				return false
+			}
+		}
+		return true
+	})
+
+	// upload through follower, it's forwarded to leader
+	var followers = c.Followers()
+	var content = make([]byte, 100*1024+7)
+	for i := range content {
+		content[i] = byte(rand.N(256))
+	}
+	var status, info = UploadFile(t, c.Servers[followers[0]].URL+"/api/upload", "", "test.bin", content)
+	if status != http.StatusOK {
+		t.Fatalf("upload status %d", status)
+	}
+	if info.Size != int64(len(content)) || len(info.Chunks) != len(nodes) {
+		t.Fatalf("uploaded file has size %d and %d chunks", info.Size, len(info.Chunks))
+	}
+
+	// download from other follower, it reads from nodes by own indexes
+	var download = func() (b []byte, status int) {
+		var resp, err = http.Get(fmt.Sprintf("%s/api/download?id=%d", c.Servers[followers[1]].URL, info.FileID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if b, err = io.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		return b, resp.StatusCode
+	}
+	var got []byte
+	WaitFor(t, 10*time.Second, "file at follower", func() bool {
+		var status int
+		got, status = download()
+		return status == http.StatusOK
+	})
+	if !bytes.Equal(got, content) {
+		t.Fatalf("downloaded content differs, got %d bytes, expected %d bytes", len(got), len(content))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"runtime"
 	"strings"
@@ -13,7 +14,10 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	"google.golang.org/grpc/grpclog"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -293,6 +297,15 @@ var handwg sync.WaitGroup
 // AjaxMiddleware is base handler middleware for AJAX API calls.
 func AjaxMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// take request ID given by client, or make new one
+		var id = r.Header.Get(telemetry.RequestIDHeader)
+		if id == "" || len(id) > telemetry.MaxRequestIDLen {
+			id = telemetry.NewRequestID()
+		}
+		w.Header().Set(telemetry.RequestIDHeader, id)
+		r = r.WithContext(telemetry.WithRequestID(r.Context(), id))
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("dfs.request.id", id))
+
 		defer func() {
 			if what := recover(); what != nil {
 				var err error
@@ -309,7 +322,7 @@ func AjaxMiddleware(next http.Handler) http.Handler {
 				var buf [2048]byte
 				var stacklen = runtime.Stack(buf[:], false)
 				var str = string(buf[:stacklen])
-				grpclog.Errorln(str)
+				slog.ErrorContext(r.Context(), "panic at handler", "error", err, "stack", str)
 				WriteRet(w, r, http.StatusInternalServerError, MakeErrPanic(err, AECpanic, str))
 			}
 		}()
@@ -331,16 +344,38 @@ func AjaxMiddleware(next http.Handler) http.Handler {
 }
 
 // RegisterRoutes puts application routes to given router.
-func RegisterRoutes(gmux *Router) {
+func (f *Front) RegisterRoutes(gmux *Router) {
+	// metrics in Prometheus text format
+	gmux.Path("/metrics").Handler(promhttp.Handler())
+	// cluster status page
+	gmux.Path("/dashboard").HandlerFunc(dashboardPage)
+
 	// API routes
 	var api = gmux.PathPrefix("/api").Subrouter()
+	api.Use(TracingMiddleware)
+	api.Use(MetricsMiddleware)
 	api.Use(AjaxMiddleware)
+	api.Use(AuthMiddleware)
 	api.Path("/ping").HandlerFunc(pingAPI)
-	api.Path("/nodesize").HandlerFunc(nodesizeAPI)
-	api.Path("/upload").Methods("POST", "PUT").HandlerFunc(uploadAPI)
-	api.Path("/download").HandlerFunc(downloadAPI)
-	api.Path("/fileinfo").HandlerFunc(fileinfoAPI)
-	api.Path("/remove").HandlerFunc(removeAPI)
-	api.Path("/clear").HandlerFunc(clearAPI)
-	api.Path("/addnode").HandlerFunc(addnodeAPI)
+	api.Path("/nodesize").HandlerFunc(Allow(RoleReader, f.nodesizeAPI))
+	api.Path("/cluster").HandlerFunc(Allow(RoleReader, f.clusterAPI))
+	api.Path("/bucketsize").HandlerFunc(Allow(RoleReader, f.bucketsizeAPI))
+	api.Path("/bucket/create").HandlerFunc(f.Leader(Allow(RoleAdmin, f.bucketcreateAPI)))
+	api.Path("/bucket/list").HandlerFunc(Allow(RoleReader, f.bucketlistAPI))
+	api.Path("/bucket/delete").HandlerFunc(f.Leader(Allow(RoleWriter, f.bucketdeleteAPI)))
+	api.Path("/list").HandlerFunc(Allow(RoleReader, f.listAPI))
+	api.Path("/upload").Methods("POST", "PUT").HandlerFunc(f.Leader(Allow(RoleWriter, f.uploadAPI)))
+	api.Path("/download").HandlerFunc(Allow(RoleReader, f.downloadAPI))
+	api.Path("/fileinfo").HandlerFunc(Allow(RoleReader, f.fileinfoAPI))
+	api.Path("/remove").HandlerFunc(f.Leader(Allow(RoleWriter, f.removeAPI)))
+	api.Path("/restore").HandlerFunc(f.Leader(Allow(RoleWriter, f.restoreAPI)))
+	api.Path("/prune").HandlerFunc(f.Leader(Allow(RoleWriter, f.pruneAPI)))
+	api.Path("/acl").HandlerFunc(f.Leader(Allow(RoleReader, f.aclAPI)))
+	api.Path("/presign").HandlerFunc(Allow(RoleReader, f.presignAPI))
+	api.Path("/clear").HandlerFunc(f.Leader(Allow(RoleAdmin, f.clearAPI)))
+	api.Path("/addnode").HandlerFunc(f.Leader(Allow(RoleAdmin, f.addnodeAPI)))
+	api.Path("/gc").HandlerFunc(f.Leader(Allow(RoleAdmin, f.gcAPI)))
+	api.Path("/gc/run").HandlerFunc(f.Leader(Allow(RoleAdmin, f.gcrunAPI)))
+	api.Path("/fsck").HandlerFunc(f.Leader(Allow(RoleAdmin, f.fsckAPI)))
+	api.Path("/lifecycle").HandlerFunc(f.Leader(Allow(RoleAdmin, f.lifecycleAPI)))
 }
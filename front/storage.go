@@ -5,25 +5,68 @@ import (
 	"encoding/xml"
 	"errors"
 	"io"
+	"log/slog"
 	"mime/multipart"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/schwarzlichtbezirk/dfs/pb"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/grpclog"
 )
 
+// ACL is access control list of file. Entries of lists are user names,
+// or group names with "group:" prefix. Write access includes read access.
+type ACL struct {
+	Read       []string `json:"read,omitempty" yaml:"read,omitempty" xml:"read>entry,omitempty"`
+	Write      []string `json:"write,omitempty" yaml:"write,omitempty" xml:"write>entry,omitempty"`
+	PublicRead bool     `json:"public_read,omitempty" yaml:"public_read,omitempty" xml:"public_read,omitempty"`
+}
+
 // FileInfo is file information about chunks placed at nodes.
 type FileInfo struct {
 	XMLName xml.Name `json:"-" yaml:"-" xml:"fi"`
 
-	FileID int64       `json:"file_id" yaml:"file_id" xml:"file_id"`
-	Name   string      `json:"name" yaml:"name" xml:"name"`
-	Size   int64       `json:"size" yaml:"size" xml:"size"`
-	MIME   string      `json:"mime" yaml:"mime" xml:"mime"`
-	Chunks []*pb.Range `json:"chunks" yaml:"chunks" xml:"chunks>range"`
+	FileID int64  `json:"file_id" yaml:"file_id" xml:"file_id"`
+	Bucket string `json:"bucket" yaml:"bucket" xml:"bucket"`
+	Name   string `json:"name" yaml:"name" xml:"name"`
+	// version of file with this name at bucket, it's zero for files uploaded before versioning
+	Version int    `json:"version,omitempty" yaml:"version,omitempty" xml:"version,omitempty"`
+	Size    int64  `json:"size" yaml:"size" xml:"size"`
+	MIME    string `json:"mime" yaml:"mime" xml:"mime"`
+	// SHA-256 of file content in hex, it's empty for files uploaded before checksums
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty" xml:"checksum,omitempty"`
+	Owner    string `json:"owner" yaml:"owner" xml:"owner"`
+	ACL      ACL    `json:"acl" yaml:"acl" xml:"acl"`
+	// storage class, chunks are placed only at nodes of tier with the same label
+	Class string `json:"class,omitempty" yaml:"class,omitempty" xml:"class,omitempty"`
+	// time of upload, it's zero for files uploaded before it was recorded
+	Created unix_t      `json:"created,omitempty" yaml:"created,omitempty" xml:"created,omitempty"`
+	Chunks  []*pb.Range `json:"chunks" yaml:"chunks" xml:"chunks>range"`
+}
+
+// CanRead returns true if given principal has read access to file.
+func (fi *FileInfo) CanRead(p *Principal) bool {
+	return p.Role == RoleAdmin || p.Name == fi.Owner || fi.ACL.PublicRead ||
+		p.matches(fi.ACL.Read) || p.matches(fi.ACL.Write)
+}
+
+// CanWrite returns true if given principal has write access to file.
+func (fi *FileInfo) CanWrite(p *Principal) bool {
+	return p.Role == RoleAdmin || p.Name == fi.Owner || p.matches(fi.ACL.Write)
+}
+
+// CanChangeACL returns true if given principal can change owner and ACL of file.
+func (fi *FileInfo) CanChangeACL(p *Principal) bool {
+	return p.Role == RoleAdmin || p.Name == fi.Owner
 }
 
 type NodeInfo struct {
@@ -35,6 +78,28 @@ type NodeInfo struct {
 	SumSize int64
 	// NumChunks is number of chunks saved on node.
 	NumChunks int
+
+	// conn is gRPC connection to node, nil if address is invalid.
+	conn *grpc.ClientConn
+	// connection was started, it's used only by nodes dialer of storage
+	dialed bool
+	// node is connected and passed handshake
+	ready atomic.Bool
+	// node was registered, and it does not send heartbeats to registry
+	departed atomic.Bool
+	// time of last heartbeat received by registry, zero if node is not registered
+	regbeat time.Time
+	// closed when node becomes available at first time
+	connected chan struct{}
+	cononce   sync.Once
+	// numbers of all calls to node and failed calls
+	calls, fails atomic.Int64
+	// node identity and build, received on handshake
+	info *pb.NodeInfo
+	// time of last successful heartbeat
+	lastseen time.Time
+	// mutex for node information and heartbeat time
+	hbmux sync.RWMutex
 }
 
 type Storage struct {
@@ -47,60 +112,275 @@ type Storage struct {
 	nodmux sync.RWMutex
 	// FIMap is files database with fileID/FileInfo keys/values.
 	FIMap sync.Map
+	// Buckets is buckets database with name/Bucket keys/values.
+	Buckets map[string]*Bucket
+	// mutex for Buckets map access and buckets usage.
+	bktmux sync.RWMutex
+	// mutex for versions numbering of files.
+	vermux sync.Mutex
+	// signals to nodes dialer that nodes list was changed
+	nodesig chan void
+}
+
+// NewStorage creates empty storage with default bucket.
+func NewStorage() *Storage {
+	return &Storage{
+		Buckets: map[string]*Bucket{
+			DefaultBucket: {
+				Name:    DefaultBucket,
+				Created: UnixJSNow(),
+			},
+		},
+		nodesig: make(chan void, 1),
+	}
 }
 
-// Storage is singleton
-var storage Storage
+// NewNodeInfo creates information of node with given address.
+// Node has no connection until RunGRPC call.
+func NewNodeInfo(addr string) *NodeInfo {
+	return &NodeInfo{
+		Addr:      addr,
+		connected: make(chan struct{}),
+	}
+}
 
-// RunGRPC establishes gRPC connection for given node.
+// NodeCredentials returns transport credentials for gRPC connections to nodes.
+func NodeCredentials() credentials.TransportCredentials {
+	if nodecerts == nil {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(nodecerts.ClientConfig())
+}
+
+// RunGRPC establishes gRPC connection for given node. Connection is made
+// in background, and node becomes available after successful handshake.
 func (node *NodeInfo) RunGRPC() {
-	grpcwg.Add(1)
+	slog.Info("grpc connection wait", "addr", node.Addr)
+	var options = []grpc.DialOption{
+		grpc.WithTransportCredentials(NodeCredentials()),
+		grpc.WithChainUnaryInterceptor(node.unaryErrors, telemetry.UnaryClientRequestID),
+		grpc.WithChainStreamInterceptor(node.streamErrors, telemetry.StreamClientRequestID),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  cfg.ReconnectBaseDelay,
+				Multiplier: backoff.DefaultConfig.Multiplier,
+				Jitter:     backoff.DefaultConfig.Jitter,
+				MaxDelay:   cfg.ReconnectMaxDelay,
+			},
+			MinConnectTimeout: cfg.ApiTimeout,
+		}),
+	}
+	var conn, err = grpc.NewClient(node.Addr, options...)
+	if err != nil { // given address can not be parsed
+		slog.Error("fail to dial", "addr", node.Addr, "error", err)
+		node.cononce.Do(func() { close(node.connected) }) // nothing to wait
+		return
+	}
+	node.conn = conn
+	node.Client = pb.NewDataGuideClient(conn)
+
 	exitwg.Add(1)
 	go func() {
 		defer exitwg.Done()
 
-		var conn *grpc.ClientConn
-		var err error
-		var ctx, cancel = context.WithCancel(context.Background())
+		// check node health and follow connection state until exit signal
+		exitwg.Add(1)
 		go func() {
-			defer grpcwg.Done()
-			defer cancel()
-
-			grpclog.Infof("grpc connection wait on %s\n", node.Addr)
-			var options = []grpc.DialOption{
-				grpc.WithTransportCredentials(insecure.NewCredentials()),
-				grpc.WithBlock(),
-			}
-			conn, err = grpc.DialContext(ctx, node.Addr, options...)
-			node.Client = pb.NewDataGuideClient(conn)
+			defer exitwg.Done()
+			node.Heartbeat(conn)
 		}()
-		// wait until connect will be established or have got exit signal
+		node.Watch(conn)
+		node.ready.Store(false)
+
+		if err := conn.Close(); err != nil {
+			slog.Error("grpc disconnect", "addr", node.Addr, "error", err)
+		} else {
+			slog.Info("grpc disconnected", "addr", node.Addr)
+		}
+	}()
+}
+
+// WaitNodes waits until all given nodes of storage will be connected,
+// but not longer than startup timeout. In degraded start mode remaining
+// nodes are connected in background, otherwise ErrStartupTimeout is
+// returned. Returns context error if exit signal was received.
+func (s *Storage) WaitNodes(nodes []*NodeInfo) error {
+	var timer = time.NewTimer(cfg.StartupTimeout)
+	defer timer.Stop()
+	for _, node := range nodes {
 		select {
-		case <-ctx.Done():
+		case <-node.connected:
+		case <-timer.C:
+			if !cfg.DegradedStart {
+				return ErrStartupTimeout
+			}
+			slog.Warn("not all nodes are connected, continue in degraded mode",
+				"ready", len(s.AvailableNodes()), "nodes", len(nodes))
+			return nil
 		case <-exitctx.Done():
-			grpclog.Infof("grpc connection canceled on %s\n", node.Addr)
-			return
+			return exitctx.Err()
+		}
+	}
+	return nil
+}
+
+// FindNode returns index of node with given address and node itself,
+// or -1 and nil if there is no such node.
+func (s *Storage) FindNode(addr string) (idx int, node *NodeInfo) {
+	s.nodmux.RLock()
+	defer s.nodmux.RUnlock()
+	for i, n := range s.Nodes {
+		if n.Addr == addr {
+			return i, n
+		}
+	}
+	return -1, nil
+}
+
+// AddNode appends node with given address if there is no node with such
+// address yet. Returns index of node, node itself, and true if it was found.
+func (s *Storage) AddNode(addr string) (idx int, node *NodeInfo, has bool) {
+	s.nodmux.Lock()
+	defer s.nodmux.Unlock()
+	for i, n := range s.Nodes {
+		if n.Addr == addr {
+			return i, n, true
+		}
+	}
+	node = NewNodeInfo(addr)
+	idx = len(s.Nodes) // get size, it will be index
+	s.Nodes = append(s.Nodes, node)
+	s.NotifyNodes()
+	return
+}
+
+// NotifyNodes signals to nodes dialer that nodes list was changed.
+// It does not block, the dialer is made outside of metadata changes.
+func (s *Storage) NotifyNodes() {
+	select {
+	case s.nodesig <- void{}:
+	default: // signal is pending already
+	}
+}
+
+// RunNodes starts nodes dialer, it establishes gRPC connections to nodes
+// added to storage, until exit signal. Nodes are added by metadata changes,
+// that must be deterministic and have no side effects, so connections
+// are made here.
+func (s *Storage) RunNodes() {
+	var dial = func() {
+		s.nodmux.RLock()
+		var nodes = s.Nodes
+		s.nodmux.RUnlock()
+		for _, node := range nodes {
+			if !node.dialed {
+				node.dialed = true
+				node.RunGRPC()
+			}
+		}
+	}
+
+	dial()
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case <-s.nodesig:
+				dial()
+			}
+		}
+	}()
+}
+
+// AvailableNodes returns indexes of nodes available for reads and writes.
+func (s *Storage) AvailableNodes() (ids []int64) {
+	s.nodmux.RLock()
+	defer s.nodmux.RUnlock()
+	for i, node := range s.Nodes {
+		if node.Available() {
+			ids = append(ids, int64(i))
 		}
+	}
+	return
+}
 
+// CommitChunks commits staged chunks of uploaded file at nodes.
+// Chunks at nodes that do not support staged writes are already stored.
+func (s *Storage) CommitChunks(ctx context.Context, chunks []*pb.Range) (err error) {
+	for _, rng := range chunks {
+		if rng.To == rng.From {
+			continue // empty chunks are not written
+		}
+		s.nodmux.RLock()
+		var node = s.Nodes[rng.NodeId]
+		s.nodmux.RUnlock()
+		if !node.HasFeature(pb.FeatureCommit) {
+			continue
+		}
+		var res *pb.Range
+		var ctx, cancel = context.WithTimeout(ctx, cfg.ApiTimeout)
+		res, err = node.Client.Commit(ctx, rng.ChunkID())
+		cancel()
 		if err != nil {
-			grpclog.Errorf("fail to dial on %s: %v", node.Addr, err)
-			exitfn()
 			return
 		}
-		grpclog.Infof("grpc connection established on %s\n", node.Addr)
+		if res.FileId == 0 { // staged chunk was expired or node was restarted
+			err = ErrNotStaged
+			return
+		}
+	}
+	return
+}
 
-		// wait for exit signal
-		<-exitctx.Done()
+// RemoveChunks removes chunks of file from nodes to abort upload.
+// Errors are ignored, it's already failed state. Chunks left at nodes
+// are expired if they are staged, or are collected as garbage.
+func (s *Storage) RemoveChunks(chunks []*pb.Range) {
+	for _, rng := range chunks {
+		s.nodmux.RLock()
+		var node = s.Nodes[rng.NodeId]
+		s.nodmux.RUnlock()
+		if !node.Available() {
+			continue
+		}
+		var ctx, cancel = context.WithTimeout(context.Background(), cfg.ApiTimeout)
+		node.Client.Remove(ctx, rng.ChunkID())
+		cancel()
+	}
+}
 
-		if err := conn.Close(); err != nil {
-			grpclog.Errorf("grpc disconnect on %s: %v\n", node.Addr, err)
-		} else {
-			grpclog.Infof("grpc disconnected on %s\n", node.Addr)
+// FeatureNodes returns indexes of nodes from given list
+// that support given optional feature.
+func (s *Storage) FeatureNodes(ids []int64, feature string) (list []int64) {
+	s.nodmux.RLock()
+	defer s.nodmux.RUnlock()
+	for _, id := range ids {
+		if s.Nodes[id].HasFeature(feature) {
+			list = append(list, id)
 		}
-	}()
+	}
+	return
 }
 
-func (s *Storage) MakeFileInfo(handler *multipart.FileHeader) (info *FileInfo) {
+// TierNodes returns indexes of nodes from given list
+// that belong to storage tier with given label.
+func (s *Storage) TierNodes(ids []int64, tier string) (list []int64) {
+	s.nodmux.RLock()
+	defer s.nodmux.RUnlock()
+	for _, id := range ids {
+		if s.Nodes[id].Tier() == tier {
+			list = append(list, id)
+		}
+	}
+	return
+}
+
+// MakeFileInfo creates new file information with unique ID for uploaded file.
+func (s *Storage) MakeFileInfo(handler *multipart.FileHeader, bucket, owner string) (info *FileInfo) {
 	// make file ID
 	var fid = atomic.AddInt64(&s.idconter, 1)
 	// extract MIME type
@@ -110,20 +390,27 @@ func (s *Storage) MakeFileInfo(handler *multipart.FileHeader) (info *FileInfo) {
 	}
 	// inits file info
 	info = &FileInfo{
-		FileID: fid,
-		Name:   handler.Filename,
-		Size:   handler.Size,
-		MIME:   mime,
+		FileID:  fid,
+		Bucket:  bucket,
+		Name:    handler.Filename,
+		Size:    handler.Size,
+		MIME:    mime,
+		Owner:   owner,
+		Created: UnixJSNow(),
 	}
 	return
 }
 
-func (s *Storage) NewReader(fi *FileInfo) io.ReadSeeker {
-	return &NodesReader{s, fi, 0}
+// NewReader returns reader for content of file placed at nodes.
+// Given context is used for all calls to nodes.
+func (s *Storage) NewReader(ctx context.Context, fi *FileInfo) *NodesReader {
+	return &NodesReader{s, fi, ctx, 0}
 }
 
 // AddFileInfo adds file information to nodes storage.
 func (s *Storage) AddFileInfo(fi *FileInfo) {
+	s.Account(fi.Bucket, fi.Size)
+
 	// update statistics
 	s.nodmux.Lock()
 	for _, rng := range fi.Chunks {
@@ -139,7 +426,10 @@ func (s *Storage) AddFileInfo(fi *FileInfo) {
 // DelFileInfo deletes file information from nodes storage.
 func (s *Storage) DelFileInfo(fi *FileInfo) {
 	// delete itself
-	s.FIMap.Delete(fi.FileID)
+	if _, ok := s.FIMap.LoadAndDelete(fi.FileID); !ok {
+		return // was deleted by concurrent call
+	}
+	s.Release(fi.Bucket, fi.Size)
 
 	// update statistics
 	s.nodmux.Lock()
@@ -150,6 +440,23 @@ func (s *Storage) DelFileInfo(fi *FileInfo) {
 	s.nodmux.Unlock()
 }
 
+// SetACL replaces owner and ACL of file with given ID. File information
+// is immutable, so it's replaced by modified copy. Returns the copy,
+// or nil if file is not found.
+func (s *Storage) SetACL(fid int64, owner string, acl ACL) *FileInfo {
+	for {
+		var data, ok = s.FIMap.Load(fid)
+		if !ok {
+			return nil
+		}
+		var info = *data.(*FileInfo) // make copy
+		info.Owner, info.ACL = owner, acl
+		if s.FIMap.CompareAndSwap(fid, data, &info) {
+			return &info
+		}
+	}
+}
+
 // Clear performs safe and quick delete of all stored data.
 func (s *Storage) Clear() {
 	// below assignment to absolute values, so lock performs to whole content
@@ -159,6 +466,14 @@ func (s *Storage) Clear() {
 	// reset files info map
 	s.FIMap = sync.Map{}
 
+	// reset buckets usage
+	s.bktmux.Lock()
+	for _, bkt := range s.Buckets {
+		bkt.Bytes = 0
+		bkt.Objects = 0
+	}
+	s.bktmux.Unlock()
+
 	// update statistics
 	for _, node := range s.Nodes {
 		node.NumChunks = 0
@@ -169,28 +484,38 @@ func (s *Storage) Clear() {
 	s.idconter = 0
 }
 
-// FindByName returns ID of first founded file record with given name, or 0 if it is not found.
-func (s *Storage) FindIdByName(name string) (fid int64) {
+// FindIdByName returns ID of latest version of file with given name
+// at given bucket, or 0 if it is not found.
+func (s *Storage) FindIdByName(bucket, name string) (fid int64) {
+	var latest *FileInfo
 	s.FIMap.Range(func(key interface{}, value interface{}) bool {
 		_ = key
 		var fi = value.(*FileInfo)
-		if fi.Name == name {
-			fid = fi.FileID
-			return false
+		if fi.Name == name && fi.Bucket == bucket && (latest == nil || latest.Older(fi)) {
+			latest = fi
 		}
 		return true
 	})
+	if latest != nil {
+		fid = latest.FileID
+	}
 	return
 }
 
-// FindFileInfo searches file record by given `fid`, or by `name` if `fid` is zero.
+// FindFileInfo searches file record by given `fid`, or by `name` at
+// `bucket` if `fid` is zero. Default bucket is used if `bucket` is empty
+// for search by name. If `bucket` is given for search by `fid`, file
+// must be placed at this bucket.
 // Returns founded record, or nil if it not found.
-func (s *Storage) FindFileInfo(fid int64, name string) (info *FileInfo) {
+func (s *Storage) FindFileInfo(bucket string, fid int64, name string) (info *FileInfo) {
 	if fid == 0 {
-		fid = s.FindIdByName(name)
+		fid = s.FindIdByName(BucketName(bucket), name)
 	}
 	if data, ok := s.FIMap.Load(fid); ok {
 		info = data.(*FileInfo)
+		if bucket != "" && info.Bucket != bucket {
+			info = nil
+		}
 	}
 	return
 }
@@ -199,11 +524,14 @@ var (
 	ErrNRBadWhence = errors.New("NodesReader.Seek: invalid whence")
 	ErrNRPosNeg    = errors.New("NodesReader.Seek: negative position")
 	ErrNROffNeg    = errors.New("NodesReader.ReadAt: negative offset")
+	ErrNRNoChunk   = errors.New("NodesReader.Read: chunk is absent on node")
+	ErrNotStaged   = errors.New("staged chunk is absent on node")
 )
 
 type NodesReader struct {
 	storage *Storage
 	info    *FileInfo
+	ctx     context.Context
 	pos     int64 // current reading index
 }
 
@@ -241,37 +569,91 @@ func (r *NodesReader) Seek(offset int64, whence int) (int64, error) {
 // readRange reads chunk of file with given range, from `off` position to `end` position.
 // Length of this range must not be larger than `b` length.
 func (r *NodesReader) readRange(off, end int64, b []byte) (n int, err error) {
-	for _, rng := range r.info.Chunks {
-		if rng.From < end && rng.To > off {
-			var from = off
-			if rng.From > off {
-				from = rng.From
-			}
-			var to = end
-			if rng.To < end {
-				to = rng.To
-			}
-			var ctx = context.Background() // no any limits
-			var in = &pb.Range{
-				NodeId: rng.NodeId,
-				FileId: rng.FileId,
-				From:   from,
-				To:     to,
-			}
-			var chunk *pb.Chunk
-			r.storage.nodmux.RLock()
-			var node = r.storage.Nodes[rng.NodeId]
-			r.storage.nodmux.RUnlock()
-			if chunk, err = node.Client.Read(ctx, in); err != nil {
-				return
-			}
-			n += copy(b[chunk.Range.From-off:], chunk.Value)
+	var ctx, span = tracer.Start(r.ctx, "read range",
+		trace.WithAttributes(
+			attribute.Int64("dfs.file.id", r.info.FileID),
+			attribute.Int64("dfs.range.from", off),
+			attribute.Int64("dfs.range.to", end),
+		))
+	defer func() {
+		if err != nil {
+			SpanError(span, err)
+		}
+		span.End()
+	}()
+
+	// chunks are ordered by offset, so find first chunk
+	// that overlaps the range by binary search
+	var chunks = r.info.Chunks
+	var first = sort.Search(len(chunks), func(i int) bool {
+		return chunks[i].To > off
+	})
+
+	// read chunks of range in parallel, striped file can have
+	// many small chunks placed at different nodes, so number of
+	// concurrent reads is limited by number of nodes
+	r.storage.nodmux.RLock()
+	var sem = make(chan void, max(len(r.storage.Nodes), 1))
+	r.storage.nodmux.RUnlock()
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	var read = func(in *pb.Range) {
+		defer wg.Done()
+		defer func() { <-sem }()
+		var cn, cerr = r.readChunk(ctx, in, off, b)
+		mux.Lock()
+		defer mux.Unlock()
+		n += cn
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for i := first; i < len(chunks) && chunks[i].From < end; i++ {
+		var rng = chunks[i]
+		if rng.To == rng.From { // skip empty chunk
+			continue
+		}
+		var in = &pb.Range{
+			NodeId: rng.NodeId,
+			FileId: rng.FileId,
+			From:   max(rng.From, off),
+			To:     min(rng.To, end),
 		}
+		sem <- void{}
+		wg.Add(1)
+		go read(in)
+	}
+	wg.Wait()
+	if err != nil {
+		return
 	}
 	r.pos = end
 	return
 }
 
+// readChunk reads given range of one chunk from its node and copies
+// the content to `b`, where `off` is file position of `b` beginning.
+func (r *NodesReader) readChunk(ctx context.Context, in *pb.Range, off int64, b []byte) (n int, err error) {
+	r.storage.nodmux.RLock()
+	var node = r.storage.Nodes[in.NodeId]
+	r.storage.nodmux.RUnlock()
+	if !node.Available() {
+		err = ErrNodeUnavailable
+		return
+	}
+	var chunk *pb.Chunk
+	if chunk, err = node.Client.Read(ctx, in); err != nil {
+		return
+	}
+	if chunk.Range == nil { // node was restarted and lost its content
+		err = ErrNRNoChunk
+		return
+	}
+	n = copy(b[chunk.Range.From-off:], chunk.Value)
+	mtrDownloaded.Add(float64(len(chunk.Value)))
+	return
+}
+
 // Read implements the io.Reader interface.
 func (r *NodesReader) Read(b []byte) (n int, err error) {
 	if r.pos >= r.info.Size {
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitNodes(t *testing.T) {
+	StartExit(t)
+	var saved = cfg.CfgStorage
+	t.Cleanup(func() { cfg.CfgStorage = saved })
+	cfg.StartupTimeout = 50 * time.Millisecond
+
+	var s = NewStorage()
+	var up, down = NewNodeInfo("up"), NewNodeInfo("down")
+	close(up.connected)
+
+	var tests = []struct {
+		degraded bool
+		nodes    []*NodeInfo
+		err      error
+	}{
+		{false, []*NodeInfo{up}, nil},
+		{true, []*NodeInfo{up}, nil},
+		{false, []*NodeInfo{up, down}, ErrStartupTimeout},
+		{true, []*NodeInfo{up, down}, nil},
+	}
+	for i, test := range tests {
+		cfg.DegradedStart = test.degraded
+		if err := s.WaitNodes(test.nodes); !errors.Is(err, test.err) {
+			t.Errorf("test #%d: expected error %v, got %v", i, test.err, err)
+		}
+	}
+
+	// exit signal breaks waiting
+	cfg.DegradedStart, cfg.StartupTimeout = false, time.Minute
+	exitfn()
+	if err := s.WaitNodes([]*NodeInfo{down}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v on exit, got %v", context.Canceled, err)
+	}
+}
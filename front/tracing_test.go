@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// clientTraceParent is W3C trace context of client of REST API.
+const clientTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// testSpans installs global tracer provider with in-memory exporter
+// at first call, and returns this exporter. Provider is installed once,
+// because tracer of front is bound to first installed global provider.
+var testSpans = sync.OnceValue(func() *tracetest.InMemoryExporter {
+	var exp = tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exp
+})
+
+func TestTracing(t *testing.T) {
+	StartExit(t)
+	var exp = testSpans()
+	exp.Reset()
+
+	var node = StartTestNode(t, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	var f = NewFront()
+	f.Storage.RunNodes()
+	f.RunNodeList([]string{node.Addr})
+	WaitFor(t, 10*time.Second, "node connection", func() bool {
+		return len(f.Storage.AvailableNodes()) == 1
+	})
+	var gmux = NewRouter()
+	f.RegisterRoutes(gmux)
+	var server = httptest.NewServer(gmux)
+	t.Cleanup(server.Close)
+
+	var body bytes.Buffer
+	var mw = multipart.NewWriter(&body)
+	var fw, _ = mw.CreateFormFile("datafile", "test.txt")
+	fw.Write([]byte("traced content"))
+	mw.Close()
+	var req, _ = http.NewRequest("POST", server.URL+"/api/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("traceparent", clientTraceParent)
+	var resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload status %d", resp.StatusCode)
+	}
+
+	// find spans of REST API call and of gRPC call at node
+	var find = func(name string) *tracetest.SpanStub {
+		for _, span := range exp.GetSpans() {
+			if span.Name == name && span.SpanKind == trace.SpanKindServer {
+				return &span
+			}
+		}
+		return nil
+	}
+	var api, write *tracetest.SpanStub
+	WaitFor(t, 5*time.Second, "spans", func() bool {
+		api, write = find("POST /api/upload"), find("dfs.DataGuide/Write")
+		return api != nil && write != nil
+	})
+
+	// REST API span continues trace of client
+	if got := api.SpanContext.TraceID().String(); got != clientTraceParent[3:35] {
+		t.Errorf("REST API span has trace %s, expected trace of client", got)
+	}
+	if got := api.Parent.SpanID().String(); got != clientTraceParent[36:52] {
+		t.Errorf("REST API span has parent %s, expected span of client", got)
+	}
+	// node span is descendant of REST API span
+	var byid = map[trace.SpanID]tracetest.SpanStub{}
+	for _, span := range exp.GetSpans() {
+		byid[span.SpanContext.SpanID()] = span
+	}
+	var chain []string
+	for span, ok := *write, true; ok; span, ok = byid[span.Parent.SpanID()] {
+		if span.SpanContext.TraceID() != api.SpanContext.TraceID() {
+			t.Fatalf("span %q has other trace", span.Name)
+		}
+		chain = append(chain, span.Name)
+		if span.SpanContext.SpanID() == api.SpanContext.SpanID() {
+			return
+		}
+	}
+	t.Errorf("node span is not descendant of REST API span, chain of parents is %q", chain)
+}
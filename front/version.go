@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+)
+
+// Older returns true if file is older version than given file.
+// Versions are ordered by numbers, and by IDs for files uploaded
+// before versioning.
+func (fi *FileInfo) Older(other *FileInfo) bool {
+	if fi.Version != other.Version {
+		return fi.Version < other.Version
+	}
+	return fi.FileID < other.FileID
+}
+
+// Versions returns all versions of file with given name at given bucket,
+// ordered from oldest to latest.
+func (s *Storage) Versions(bucket, name string) (list []*FileInfo) {
+	s.FIMap.Range(func(key any, value any) bool {
+		var fi = value.(*FileInfo)
+		if fi.Name == name && fi.Bucket == bucket {
+			list = append(list, fi)
+		}
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Older(list[j])
+	})
+	return
+}
+
+// NextVersion returns number of version for new file
+// with given name at given bucket.
+func (s *Storage) NextVersion(bucket, name string) (ver int) {
+	s.FIMap.Range(func(key any, value any) bool {
+		var fi = value.(*FileInfo)
+		if fi.Name == name && fi.Bucket == bucket {
+			ver = max(ver, fi.Version)
+		}
+		return true
+	})
+	return ver + 1
+}
+
+// CanAddVersion returns true if given principal can upload new version
+// of file with given name at given bucket, that is if there is no such
+// file yet, or principal has write access to its latest version.
+func (s *Storage) CanAddVersion(p *Principal, bucket, name string) bool {
+	var info = s.FindFileInfo(bucket, 0, name)
+	return info == nil || info.CanWrite(p)
+}
+
+// AddVersion adds file information to storage as latest version
+// of file with its name at its bucket.
+func (s *Storage) AddVersion(fi *FileInfo) {
+	s.vermux.Lock()
+	defer s.vermux.Unlock()
+	fi.Version = s.NextVersion(fi.Bucket, fi.Name)
+	s.AddFileInfo(fi)
+}
+
+// CopyVersion copies content of file to new chunks with given new file ID
+// at the same nodes. Returns information of the copy that can be added
+// as new version of file, and its chunks are removed on failure.
+// Chunks with new file ID must be protected from garbage collection
+// before copying until the copy will be added.
+func (s *Storage) CopyVersion(ctx context.Context, fi *FileInfo, fid int64) (info *FileInfo, err error) {
+	var cp = *fi // make copy
+	cp.FileID = fid
+	cp.Version = 0 // will be assigned on add
+	cp.Created = UnixJSNow()
+	cp.Chunks = make([]*pb.Range, len(fi.Chunks))
+	for i, rng := range fi.Chunks {
+		var dst = &pb.Range{
+			NodeId: rng.NodeId,
+			FileId: cp.FileID,
+			From:   rng.From,
+			To:     rng.To,
+		}
+		cp.Chunks[i] = dst
+		if rng.To == rng.From {
+			continue // empty chunks are not written
+		}
+		s.nodmux.RLock()
+		var node = s.Nodes[rng.NodeId]
+		s.nodmux.RUnlock()
+		if !node.Available() {
+			err = ErrNodeUnavailable
+		} else {
+			err = CopyChunk(ctx, node, node, rng, dst)
+		}
+		if err != nil {
+			s.RemoveChunks(cp.Chunks[:i])
+			return
+		}
+	}
+	info = &cp
+	return
+}
+
+// FindVersion searches file record by given `fid`, or by `name` at
+// `bucket` like FindFileInfo, and returns its version with given number.
+// Latest version is returned if `version` is zero.
+// Returns founded record, or nil if it not found.
+func (s *Storage) FindVersion(bucket string, fid int64, name string, version int) (info *FileInfo) {
+	if info = s.FindFileInfo(bucket, fid, name); info == nil || version == 0 {
+		return
+	}
+	for _, fi := range s.Versions(info.Bucket, info.Name) {
+		if fi.Version == version {
+			return fi
+		}
+	}
+	return nil
+}
+
+// restoreAPI adds copy of pointed version of file as its latest version,
+// pointed version is left in place. Returns file info of added version.
+func (f *Front) restoreAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Bucket  string `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+		Name    string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+		ID      int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		Version int    `json:"version,omitempty" yaml:"version,omitempty" xml:"version,omitempty"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	if arg.ID == 0 && (arg.Name == "" || arg.Version == 0) {
+		WriteError400(w, r, ErrNoData, AECrestorenoarg)
+		return
+	}
+
+	var info *FileInfo
+	if info = f.Storage.FindVersion(arg.Bucket, arg.ID, arg.Name, arg.Version); info == nil {
+		WriteError(w, r, http.StatusNotFound, ErrNotFound, AECrestoreabsent)
+		return
+	}
+	if !info.CanWrite(GetPrincipal(r)) {
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECrestoreaccess)
+		return
+	}
+
+	if err = f.Storage.Reserve(info.Bucket, info.Size); err != nil {
+		if errors.Is(err, ErrBucketAbsent) {
+			WriteError(w, r, http.StatusNotFound, err, AECrestoreabsent)
+		} else {
+			WriteError(w, r, http.StatusInsufficientStorage, err, AECrestorequota)
+		}
+		return
+	}
+	defer f.Storage.Unreserve(info.Bucket, info.Size)
+
+	// restored version is a new file with copy of old content, its chunks
+	// are not collected as garbage from start of copying until it's added
+	var fid = atomic.AddInt64(&f.Storage.idconter, 1)
+	uploads.Store(fid, struct{}{})
+	defer uploads.Delete(fid)
+	var cp *FileInfo
+	if cp, err = f.Storage.CopyVersion(context.WithoutCancel(r.Context()), info, fid); err != nil {
+		WriteError500(w, r, err, AECrestorecopy)
+		return
+	}
+
+	var res any
+	if res, err = f.ApplyCmd(&Command{Op: OpAddFile, File: cp}); err != nil {
+		f.Storage.RemoveChunks(cp.Chunks)
+		WriteError(w, r, http.StatusServiceUnavailable, err, AECrestoreapply)
+		return
+	}
+
+	WriteOK(w, r, res.(*FileInfo))
+}
+
+// pruneAPI removes old versions of pointed file. Versions are removed
+// if they are beyond given number of latest versions to keep, or if they
+// are older than given age. Latest version is never removed.
+// Returns file info of removed versions.
+func (f *Front) pruneAPI(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var arg struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"arg"`
+
+		Bucket string `json:"bucket,omitempty" yaml:"bucket,omitempty" xml:"bucket,omitempty"`
+		Name   string `json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"`
+		ID     int64  `json:"id,omitempty" yaml:"id,omitempty" xml:"id,omitempty"`
+		Keep   int    `json:"keep,omitempty" yaml:"keep,omitempty" xml:"keep,omitempty"` // number of versions to keep
+		Age    int64  `json:"age,omitempty" yaml:"age,omitempty" xml:"age,omitempty"`    // in seconds
+	}
+	var ret struct {
+		XMLName xml.Name `json:"-" yaml:"-" xml:"ret"`
+
+		List []*FileInfo `json:"list" yaml:"list" xml:"list>fi"`
+	}
+
+	// get arguments
+	if err = ParseBody(w, r, &arg); err != nil {
+		return
+	}
+	if (arg.ID == 0 && arg.Name == "") || (arg.Keep <= 0 && arg.Age <= 0) {
+		WriteError400(w, r, ErrNoData, AECprunenoarg)
+		return
+	}
+
+	var info *FileInfo
+	if info = f.Storage.FindFileInfo(arg.Bucket, arg.ID, arg.Name); info == nil {
+		WriteError(w, r, http.StatusNotFound, ErrNotFound, AECpruneabsent)
+		return
+	}
+	var p = GetPrincipal(r)
+	if !info.CanWrite(p) {
+		WriteError(w, r, http.StatusForbidden, ErrNoAccess, AECpruneaccess)
+		return
+	}
+
+	var list = f.Storage.Versions(info.Bucket, info.Name)
+	var expired = time.Now().Add(-time.Duration(arg.Age) * time.Second)
+	ret.List = []*FileInfo{}
+	for i := 0; i < len(list)-1; i++ { // skip latest version
+		var fi = list[i]
+		var beyond = arg.Keep > 0 && i < len(list)-arg.Keep
+		var old = arg.Age > 0 && fi.Created != 0 && fi.Created.Time().Before(expired)
+		if !beyond && !old || !fi.CanWrite(p) {
+			continue
+		}
+		if _, err = f.ApplyCmd(&Command{Op: OpDelFile, FileID: fi.FileID}); err != nil {
+			WriteError(w, r, http.StatusServiceUnavailable, err, AECpruneapply)
+			return
+		}
+		// chunks left at nodes are collected as garbage
+		f.Storage.RemoveChunks(fi.Chunks)
+		ret.List = append(ret.List, fi)
+	}
+
+	WriteOK(w, r, &ret)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestUploadVersionAccess(t *testing.T) {
+	StartExit(t)
+	var f, server = StartTestFront(t, 2)
+	StartTestAuth(t,
+		testUser{Name: "alice", Role: RoleWriter},
+		testUser{Name: "bob", Role: RoleWriter},
+		testUser{Name: "root", Role: RoleAdmin},
+	)
+	var url = server.URL + "/api/upload"
+
+	var tests = []struct {
+		user, name string
+		status     int
+		version    int
+	}{
+		{"alice", "doc.txt", http.StatusOK, 1},
+		{"bob", "doc.txt", http.StatusForbidden, 0}, // can not take over file of other user
+		{"alice", "doc.txt", http.StatusOK, 2},
+		{"bob", "other.txt", http.StatusOK, 1},
+		{"root", "doc.txt", http.StatusOK, 3}, // admin can write any file
+		{"bob", "doc.txt", http.StatusForbidden, 0},
+	}
+	for i, test := range tests {
+		var status, info = UploadFile(t, url, test.user, test.name, []byte("content of "+test.user))
+		if status != test.status {
+			t.Fatalf("test #%d: user %s uploads %s with status %d, expected %d",
+				i, test.user, test.name, status, test.status)
+		}
+		if status == http.StatusOK && info.Version != test.version {
+			t.Errorf("test #%d: uploaded version %d, expected %d", i, info.Version, test.version)
+		}
+	}
+	// refused uploads did not add versions
+	if fi := f.Storage.FindFileInfo(DefaultBucket, 0, "doc.txt"); fi == nil || fi.Owner != "root" {
+		t.Errorf("latest version of file is not of admin")
+	}
+
+	// writer given by ACL can upload new version
+	if _, err := f.ApplyCmd(&Command{Op: OpSetACL, FileID: f.Storage.FindIdByName(DefaultBucket, "doc.txt"),
+		Owner: "root", ACL: ACL{Write: []string{"bob"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := UploadFile(t, url, "bob", "doc.txt", []byte("granted")); status != http.StatusOK {
+		t.Errorf("writer by ACL uploads with status %d", status)
+	}
+}
+
+func TestRestoreVersion(t *testing.T) {
+	StartExit(t)
+	var f, server = StartTestFront(t, 2)
+	var url = server.URL + "/api/upload"
+	for _, content := range []string{"first content", "second content"} {
+		if status, _ := UploadFile(t, url, "", "doc.txt", []byte(content)); status != http.StatusOK {
+			t.Fatalf("upload status %d", status)
+		}
+	}
+
+	if status := CallAPI(t, server.URL+"/api/restore", "", `{"name":"doc.txt","version":1}`, nil); status != http.StatusOK {
+		t.Fatalf("restore status %d", status)
+	}
+	var list = f.Storage.Versions(DefaultBucket, "doc.txt")
+	if len(list) != 3 || list[2].Version != 3 {
+		t.Fatalf("expected 3 versions after restore, got %d", len(list))
+	}
+	if _, ok := uploads.Load(list[2].FileID); ok {
+		t.Errorf("restored version is left protected from garbage collection")
+	}
+	var b = make([]byte, list[2].Size)
+	if _, err := f.Storage.NewReader(context.Background(), list[2]).ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "first content" {
+		t.Errorf("restored version has content %q", b)
+	}
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/jessevdk/go-flags"
-	"google.golang.org/grpc/grpclog"
+	"github.com/schwarzlichtbezirk/dfs/cert"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
 )
 
 var (
@@ -20,18 +25,48 @@ var (
 	exitfn  context.CancelFunc
 	// wait group for all server goroutines
 	exitwg sync.WaitGroup
-	// wait group for grpc goroutines
-	grpcwg sync.WaitGroup
+	// TLS certificates for gRPC connections to nodes, nil if TLS is off
+	nodecerts *cert.Reloader
+	// TLS certificates for web server, nil if there is no TLS ports
+	webcerts *cert.Reloader
+	// flushes pending spans and stops tracing
+	tracingdone func(context.Context) error
 )
 
+// Front is instance of front service. It has storage metadata with nodes,
+// and replica of metadata at fronts cluster. Handlers and background jobs
+// of service are methods of front, so several fronts can work in one process.
+type Front struct {
+	Storage *Storage
+	Replica *Replica // nil if fronts cluster is off
+	// Resolver resolves host names of discovered nodes.
+	Resolver Resolver
+
+	// gcrunning is set while garbage collection is in progress.
+	gcrunning atomic.Bool
+	// gcreport is report of last garbage collection.
+	gcreport atomic.Pointer[GCReport]
+	// fsckrunning is set while consistency check is in progress.
+	fsckrunning atomic.Bool
+}
+
+// NewFront creates front with empty storage.
+func NewFront() *Front {
+	return &Front{
+		Storage:  NewStorage(),
+		Resolver: net.DefaultResolver,
+	}
+}
+
 func init() {
-	grpclog.SetLoggerV2(grpclog.NewLoggerV2(os.Stdout, os.Stderr, os.Stderr))
+	telemetry.SetupLog(os.Stdout, telemetry.FormatText, "info")
 }
 
 // Init performs global data initialization.
 func Init() {
-	grpclog.Infof("version: %s, builton: %s\n", buildvers, builddate)
-	grpclog.Infoln("starts")
+	// first iteration, settings from command line to find configuration
+	ParseConfig()
+	slog.Info("starts", "version", buildvers, "builton", builddate)
 
 	// create context and wait the break
 	exitctx, exitfn = context.WithCancel(context.Background())
@@ -49,16 +84,16 @@ func Init() {
 		select {
 		case <-exitctx.Done():
 			if errors.Is(exitctx.Err(), context.DeadlineExceeded) {
-				grpclog.Infoln("shutting down by timeout")
+				slog.Info("shutting down by timeout")
 			} else if errors.Is(exitctx.Err(), context.Canceled) {
-				grpclog.Infoln("shutting down by cancel")
+				slog.Info("shutting down by cancel")
 			} else {
-				grpclog.Infoln("shutting down by %s", exitctx.Err().Error())
+				slog.Info("shutting down", "reason", exitctx.Err())
 			}
 		case <-sigint:
-			grpclog.Infoln("shutting down by break")
+			slog.Info("shutting down by break")
 		case <-sigterm:
-			grpclog.Infoln("shutting down by process termination")
+			slog.Info("shutting down by process termination")
 		}
 		signal.Stop(sigint)
 		signal.Stop(sigterm)
@@ -69,59 +104,172 @@ func Init() {
 
 	// get confiruration path
 	if ConfigPath, err = DetectConfigPath(); err != nil {
-		grpclog.Fatal(err)
+		telemetry.Fatal("can not detect configuration path", "error", err)
 	}
-	grpclog.Infof("config path: %s\n", ConfigPath)
+	slog.Info("configuration path detected", "path", ConfigPath)
 
-	log.Println(cfg.PortHTTP)
 	if err = ReadYaml(cfgfile, &cfg); err != nil {
-		grpclog.Fatalf("can not read '%s' file: %v\n", cfgfile, err)
+		telemetry.Fatal("can not read configuration file", "file", cfgfile, "error", err)
 	}
-	grpclog.Infof("loaded '%s'\n", cfgfile)
-	log.Println(cfg.PortHTTP)
 	// second iteration, rewrite settings from config file
 	if _, err = flags.NewParser(&cfg, flags.PassDoubleDash).Parse(); err != nil {
 		panic("no way to here")
 	}
+	if err = telemetry.SetupLog(os.Stdout, cfg.Format, cfg.Level); err != nil {
+		telemetry.Fatal("can not setup logging", "error", err)
+	}
+	slog.Info("configuration loaded", "file", cfgfile)
 
 	// correct config
 	if cfg.MinNodeChunkSize <= 0 {
 		cfg.MinNodeChunkSize = 4 * 1024
-		grpclog.Warningf("'min-node-chunk-size' is adjusted to %d\n", cfg.MinNodeChunkSize)
+		slog.Warn("'min-node-chunk-size' is adjusted", "value", cfg.MinNodeChunkSize)
 	}
 	if cfg.StreamChunkSize <= 0 {
 		cfg.StreamChunkSize = 512
-		grpclog.Warningf("'stream-chunk-size' is adjusted to %d\n", cfg.StreamChunkSize)
+		slog.Warn("'stream-chunk-size' is adjusted", "value", cfg.StreamChunkSize)
 	}
-	grpclog.Infof("expects %d nodes\n", len(cfg.NodeList))
-	storage.Nodes = make([]*NodeInfo, len(cfg.NodeList))
+	// load TLS certificates
+	if cfg.UseTLS {
+		if nodecerts, err = cert.NewReloader(cfg.CfgNodeTLS.CertFile, cfg.CfgNodeTLS.KeyFile, cfg.CAFile); err != nil {
+			telemetry.Fatal("can not load TLS certificates for nodes", "error", err)
+		}
+		if cfg.CfgNodeTLS.CertFile != "" {
+			slog.Info("gRPC uses mutual TLS")
+		} else {
+			slog.Info("gRPC uses TLS")
+		}
+	}
+
+	if len(cfg.PortTLS) > 0 {
+		if webcerts, err = cert.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, ""); err != nil {
+			telemetry.Fatal("can not load TLS certificates for web server", "error", err)
+		}
+		if webcerts.Certificate() == nil {
+			telemetry.Fatal("'tls-cert-file' is not given for 'port-tls' listeners")
+		}
+		// reload certificates on files changes
+		if cfg.TLSReloadPeriod > 0 {
+			go webcerts.Watch(exitctx, cfg.TLSReloadPeriod, func(err error) {
+				if err != nil {
+					slog.Error("can not reload TLS certificates for web server", "error", err)
+				} else {
+					slog.Info("TLS certificates for web server reloaded")
+				}
+			})
+		}
+	}
+
+	// setup tracing
+	if tracingdone, err = telemetry.Setup(exitctx, gitname+"-front", buildvers, cfg.Exporter, cfg.Endpoint); err != nil {
+		telemetry.Fatal("can not setup tracing", "error", err)
+	}
+	if cfg.Exporter != telemetry.ExporterNone {
+		slog.Info("spans are exported", "exporter", cfg.Exporter)
+	}
+
+	// load credentials
+	if err = auth.Load(); err != nil {
+		telemetry.Fatal("can not load authentication credentials", "error", err)
+	}
+	if !auth.Enabled() {
+		slog.Warn("authentication is disabled, REST API is open for everyone")
+	}
+
+	// reload certificates and credentials on SIGHUP
+	go func() {
+		var sighup = make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		defer signal.Stop(sighup)
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case <-sighup:
+				ReloadCerts()
+				if err := auth.Load(); err != nil {
+					slog.Error("can not reload authentication credentials", "error", err)
+				} else {
+					slog.Info("authentication credentials reloaded")
+				}
+			}
+		}
+	}()
+
+	// skip empty entries, nodes list can be empty if nodes join by registry
+	cfg.NodeList = slices.DeleteFunc(cfg.NodeList, func(addr string) bool { return addr == "" })
+	slog.Info("expects nodes", "count", len(cfg.NodeList))
 }
 
-// Run launches server listeners.
-func Run(gmux *Router) {
-	// starts gRPC clients
-	for i, addr := range cfg.NodeList {
-		var node = &NodeInfo{
-			Addr:    addr,
-			SumSize: 0,
+// ReloadCerts reads again all used TLS certificates.
+func ReloadCerts() {
+	if nodecerts != nil {
+		if err := nodecerts.Reload(); err != nil {
+			slog.Error("can not reload TLS certificates for nodes", "error", err)
+		} else {
+			slog.Info("TLS certificates for nodes reloaded")
+		}
+	}
+	if webcerts != nil {
+		if err := webcerts.Reload(); err != nil {
+			slog.Error("can not reload TLS certificates for web server", "error", err)
+		} else {
+			slog.Info("TLS certificates for web server reloaded")
 		}
-		storage.Nodes[i] = node
-		node.RunGRPC()
 	}
-	// wait until all grpc starts
-	grpcwg.Wait()
+}
+
+// RedirectHandler redirects all requests to the same URL on first TLS port.
+// Redirect is permanent and keeps method and body of request, so uploads
+// and other POST calls are repeated at TLS port.
+func RedirectHandler(w http.ResponseWriter, r *http.Request) {
+	var host = r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, err := net.SplitHostPort(EnvFmt(cfg.PortTLS[0])); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
 
-	// check on exit during grpc connecting
-	select {
-	case <-exitctx.Done():
+// Run launches server listeners.
+func (f *Front) Run(gmux *Router) {
+	// expose statistics of nodes
+	RegisterNodesMetrics(f.Storage)
+	// starts gRPC clients of added nodes
+	f.Storage.RunNodes()
+	// starts replication of metadata if fronts cluster is on
+	f.RunReplica()
+	// add nodes from configuration
+	f.RunNodeList(cfg.NodeList)
+	// starts registry to let nodes join during waiting
+	f.RunRegistry()
+	// add discovered nodes
+	f.RunDiscovery()
+	// starts collecting of orphaned chunks
+	f.RunGC()
+	// starts moving of files between storage classes
+	f.RunLifecycle()
+	// nodes list of fronts cluster is received from leader
+	if f.Replica != nil && !f.Replica.WaitLeader(cfg.StartupTimeout) {
+		slog.Warn("fronts cluster has no leader yet, continue without nodes list")
+	}
+	// wait until nodes are connected, check on exit during connecting
+	f.Storage.nodmux.RLock()
+	var nodes = f.Storage.Nodes
+	f.Storage.nodmux.RUnlock()
+	if err := f.Storage.WaitNodes(nodes); err != nil {
+		if errors.Is(err, ErrStartupTimeout) {
+			telemetry.Fatal("can not start service", "ready", len(f.Storage.AvailableNodes()),
+				"nodes", len(nodes), "error", err)
+		}
 		return
-	default:
 	}
 
 	// starts HTTP listeners
 	var httpwg sync.WaitGroup
-	for _, addr := range cfg.PortHTTP {
-		var addr = EnvFmt(addr) // localize
+	var serve = func(addr string, handler http.Handler, tlscfg *tls.Config) {
 		httpwg.Add(1)
 		exitwg.Add(1)
 		go func() {
@@ -129,7 +277,8 @@ func Run(gmux *Router) {
 
 			var server = &http.Server{
 				Addr:              addr,
-				Handler:           gmux,
+				Handler:           handler,
+				TLSConfig:         tlscfg,
 				ReadTimeout:       cfg.ReadTimeout,
 				ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 				WriteTimeout:      cfg.WriteTimeout,
@@ -137,11 +286,22 @@ func Run(gmux *Router) {
 				MaxHeaderBytes:    cfg.MaxHeaderBytes,
 			}
 
-			grpclog.Infof("start http on %s\n", addr)
+			if tlscfg != nil {
+				slog.Info("start https", "addr", addr)
+			} else {
+				slog.Info("start http", "addr", addr)
+			}
 			go func() {
 				httpwg.Done()
-				if err := server.ListenAndServe(); err != http.ErrServerClosed {
-					grpclog.Fatalf("failed to serve at all: %v", err)
+				var err error
+				if tlscfg != nil {
+					// certificates are given by TLS config
+					err = server.ListenAndServeTLS("", "")
+				} else {
+					err = server.ListenAndServe()
+				}
+				if err != http.ErrServerClosed {
+					telemetry.Fatal("failed to serve at all", "addr", addr, "error", err)
 				}
 			}()
 
@@ -154,14 +314,27 @@ func Run(gmux *Router) {
 
 			server.SetKeepAlivesEnabled(false)
 			if err := server.Shutdown(ctx); err != nil {
-				grpclog.Errorf("shutdown http on %s: %v\n", addr, err)
+				slog.Error("shutdown http", "addr", addr, "error", err)
 			} else {
-				grpclog.Infof("stop http on %s\n", addr)
+				slog.Info("stop http", "addr", addr)
 			}
 		}()
 	}
+
+	var handler http.Handler = gmux
+	if cfg.HTTPRedirect && len(cfg.PortTLS) > 0 {
+		handler = http.HandlerFunc(RedirectHandler)
+	}
+	for _, addr := range cfg.PortHTTP {
+		serve(EnvFmt(addr), handler, nil)
+	}
+	for _, addr := range cfg.PortTLS {
+		var tlscfg = webcerts.ServerConfig()
+		tlscfg.NextProtos = []string{"h2", "http/1.1"} // enable HTTP/2
+		serve(EnvFmt(addr), gmux, tlscfg)
+	}
 	httpwg.Wait()
-	grpclog.Infoln("service ready")
+	slog.Info("service ready")
 }
 
 // Done performs graceful network shutdown,
@@ -171,5 +344,11 @@ func Done() {
 	<-exitctx.Done()
 	// wait until all server threads will be stopped.
 	exitwg.Wait()
-	grpclog.Infoln("shutting down complete.")
+	// flush pending spans
+	var ctx, cancel = context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := tracingdone(ctx); err != nil {
+		slog.Error("tracing shutdown", "error", err)
+	}
+	slog.Info("shutting down complete")
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	var saved = cfg.PortTLS
+	t.Cleanup(func() { cfg.PortTLS = saved })
+
+	var tests = []struct {
+		port, target, location string
+	}{
+		{":443", "http://example.com:8008/api/upload?bucket=team1", "https://example.com/api/upload?bucket=team1"},
+		{":8443", "http://example.com:8008/api/upload", "https://example.com:8443/api/upload"},
+		{":8443", "http://example.com/api/list", "https://example.com:8443/api/list"},
+	}
+	for _, test := range tests {
+		cfg.PortTLS = []string{test.port}
+		var w = httptest.NewRecorder()
+		RedirectHandler(w, httptest.NewRequest("POST", test.target, nil))
+		// method and body of request must be kept by redirect
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: status %d, expected %d", test.target, w.Code, http.StatusPermanentRedirect)
+		}
+		if got := w.Header().Get("Location"); got != test.location {
+			t.Errorf("%s: redirected to %s, expected %s", test.target, got, test.location)
+		}
+	}
+}
@@ -1,31 +1,71 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 )
 
 // Instance of common service settings.
 var cfg struct {
-	PortGRPC string `json:"port-grpc" yaml:"port-grpc" env:"NODEPORT" short:"p" long:"portgrpc" default:":50051" description:"Port used by this node for gRPC exchange."`
+	ID            string        `json:"id" yaml:"id" env:"NODEID" long:"id" description:"Identity of node in cluster. Host name with gRPC port is used if it's not given."`
+	PortGRPC      string        `json:"port-grpc" yaml:"port-grpc" env:"NODEPORT" short:"p" long:"portgrpc" default:":50051" description:"Port used by this node for gRPC exchange."`
+	Addr          string        `json:"addr" yaml:"addr" env:"NODEADDR" long:"addr" description:"Address:port by which front reaches this node, reported on registration. Host name with gRPC port is used if it's not given."`
+	Front         []string      `json:"front" yaml:"front" env:"NODEFRONT" env-delim:";" long:"front" description:"Address:port of front registry to register this node at, can be given for each front of cluster. Node does not register if it's not given."`
+	RegTokenFile  string        `json:"reg-token-file" yaml:"reg-token-file" env:"NODEREGTOKENFILE" long:"regtoken" description:"File with shared token presented to front registry. It's needed if registry does not verify nodes by mutual TLS."`
+	PortMetrics   string        `json:"port-metrics" yaml:"port-metrics" env:"NODEMETRICSPORT" short:"m" long:"portmetrics" description:"Port of HTTP listener with Prometheus metrics at '/metrics' path. Metrics are not served if it's not given."`
+	Tier          string        `json:"tier" yaml:"tier" env:"NODETIER" long:"tier" description:"Storage tier label of node, like 'hot' for SSD or 'cold' for HDD. Files of storage class are placed only at nodes of tier with the same label."`
+	StagedTTL     time.Duration `json:"staged-ttl" yaml:"staged-ttl" env:"NODESTAGEDTTL" long:"stagedttl" default:"10m" description:"Staged chunks of uploads that were not committed during this time are deleted."`
+	KeyFile       string        `json:"key-file" yaml:"key-file" env:"NODEKEYFILE" short:"k" long:"keyfile" description:"File with encryption keys of chunks data, each line in format 'id base64key', last key is active. Keys also can be given by NODEKEY environment variable."`
+	TLSCert       string        `json:"tls-cert" yaml:"tls-cert" env:"NODETLSCERT" long:"tlscert" description:"PEM file with node certificate for gRPC over TLS. Plain connections are used if it's not given."`
+	TLSKey        string        `json:"tls-key" yaml:"tls-key" env:"NODETLSKEY" long:"tlskey" description:"PEM file with private key of node certificate."`
+	TLSCA         string        `json:"tls-ca" yaml:"tls-ca" env:"NODETLSCA" long:"tlsca" description:"PEM file with CA certificates to verify clients by mutual TLS. Clients are not verified if it's not given."`
+	LogFormat     string        `json:"log-format" yaml:"log-format" env:"NODELOGFORMAT" long:"logformat" default:"text" choice:"text" choice:"json" description:"Format of log records, 'text' or 'json'."`
+	LogLevel      string        `json:"log-level" yaml:"log-level" env:"NODELOGLEVEL" long:"loglevel" default:"info" choice:"debug" choice:"info" choice:"warn" choice:"error" description:"Minimum level of log records."`
+	TraceExporter string        `json:"trace-exporter" yaml:"trace-exporter" env:"NODETRACEEXPORTER" long:"trace" choice:"" choice:"stdout" choice:"otlp" description:"Exporter of spans, 'stdout' or 'otlp'. Spans are not recorded if it's empty."`
+	TraceEndpoint string        `json:"trace-endpoint" yaml:"trace-endpoint" env:"NODETRACEENDPOINT" long:"traceendpoint" description:"Address of OTLP collector, 'host:port' for TLS connection or URL with 'http://' scheme for plain connection."`
 }
 
 // compiled binary version, sets by compiler with command
-//    go build -ldflags="-X 'main.buildvers=%buildvers%'"
+//
+//	go build -ldflags="-X 'main.buildvers=%buildvers%'"
 var buildvers string
 
 // compiled binary build date, sets by compiler with command
-//    go build -ldflags="-X 'main.builddate=%date%'"
+//
+//	go build -ldflags="-X 'main.builddate=%date%'"
 var builddate string
 
-func init() {
+// ErrStagedTTL is "staged chunks TTL must be positive" error message.
+var ErrStagedTTL = errors.New("'staged-ttl' must be positive")
+
+// ParseConfig reads settings from command line and environment variables.
+func ParseConfig() {
 	if _, err := flags.Parse(&cfg); err != nil {
 		os.Exit(1)
 	}
-	// correct config
+}
+
+// CheckConfig corrects settings, and returns error if they can not be used.
+func CheckConfig() error {
+	if cfg.StagedTTL <= 0 {
+		return ErrStagedTTL
+	}
 	if !strings.HasPrefix(cfg.PortGRPC, ":") {
 		cfg.PortGRPC = ":" + cfg.PortGRPC
 	}
+	var host, _ = os.Hostname()
+	if cfg.ID == "" {
+		cfg.ID = host + cfg.PortGRPC
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = host + cfg.PortGRPC
+	}
+	if cfg.PortMetrics != "" && !strings.Contains(cfg.PortMetrics, ":") {
+		cfg.PortMetrics = ":" + cfg.PortMetrics
+	}
+	return nil
 }
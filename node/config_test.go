@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckConfig(t *testing.T) {
+	var tests = []struct {
+		ttl time.Duration
+		err error
+	}{
+		{10 * time.Minute, nil},
+		{time.Nanosecond, nil},
+		{0, ErrStagedTTL},
+		{-time.Minute, ErrStagedTTL},
+	}
+	var saved = cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, test := range tests {
+		cfg.StagedTTL = test.ttl
+		if err := CheckConfig(); !errors.Is(err, test.err) {
+			t.Errorf("staged TTL %v: expected error %v, got %v", test.ttl, test.err, err)
+		}
+	}
+}
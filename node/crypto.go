@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Environment variable with list of encryption keys, in format
+// "id1:base64key1;id2:base64key2", last key in list is active.
+const keyenv = "NODEKEY"
+
+var (
+	// ErrKeyFormat is "encryption key has invalid format" error message.
+	ErrKeyFormat = errors.New("encryption key has invalid format")
+	// ErrKeyAbsent is "no encryption key with given ID" error message.
+	ErrKeyAbsent = errors.New("no encryption key with given ID")
+)
+
+// Keyring holds AES-GCM ciphers by their key IDs,
+// and ID of active key that used to seal new chunks.
+type Keyring struct {
+	aeads  map[string]cipher.AEAD
+	active string
+	mux    sync.RWMutex
+}
+
+// Keyring is singleton.
+var keyring Keyring
+
+// MakeAEAD creates AES-GCM cipher for given key with 16, 24 or 32 bytes length.
+func MakeAEAD(key []byte) (aead cipher.AEAD, err error) {
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParseKey parses key pair in format "id:base64key" or "id base64key".
+func ParseKey(s string) (kid string, key []byte, err error) {
+	var pos = strings.IndexAny(s, ": \t")
+	if pos <= 0 {
+		err = ErrKeyFormat
+		return
+	}
+	kid = s[:pos]
+	if key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(s[pos+1:])); err != nil {
+		err = fmt.Errorf("key '%s': %w", kid, err)
+		return
+	}
+	return
+}
+
+// Load reads keys from key file with given name, and then from
+// environment variable. Last loaded key becomes active.
+// Previous content of keyring is replaced by loaded keys.
+func (kr *Keyring) Load(fname string) (err error) {
+	var aeads = map[string]cipher.AEAD{}
+	var active string
+	var add = func(s string) error {
+		var kid, key, err = ParseKey(s)
+		if err != nil {
+			return err
+		}
+		if aeads[kid], err = MakeAEAD(key); err != nil {
+			return fmt.Errorf("key '%s': %w", kid, err)
+		}
+		active = kid
+		return nil
+	}
+
+	if fname != "" {
+		var f *os.File
+		if f, err = os.Open(fname); err != nil {
+			return
+		}
+		defer f.Close()
+		var scanner = bufio.NewScanner(f)
+		for scanner.Scan() {
+			var line = strings.TrimSpace(scanner.Text())
+			if line == "" || line[0] == '#' {
+				continue
+			}
+			if err = add(line); err != nil {
+				return
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return
+		}
+	}
+	if val, ok := os.LookupEnv(keyenv); ok {
+		for _, s := range strings.Split(val, ";") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			if err = add(s); err != nil {
+				return
+			}
+		}
+	}
+
+	kr.mux.Lock()
+	kr.aeads, kr.active = aeads, active
+	kr.mux.Unlock()
+	return
+}
+
+// Active returns ID of key used to seal new chunks.
+// Returns empty string if encryption is off.
+func (kr *Keyring) Active() string {
+	kr.mux.RLock()
+	defer kr.mux.RUnlock()
+	return kr.active
+}
+
+// Len returns number of loaded keys.
+func (kr *Keyring) Len() int {
+	kr.mux.RLock()
+	defer kr.mux.RUnlock()
+	return len(kr.aeads)
+}
+
+// chunkAD returns additional data that binds sealed content to file ID
+// and to offset of chunk in file, so chunk can not be moved to other place.
+func chunkAD(key ChunkKey) []byte {
+	var ad [16]byte
+	binary.BigEndian.PutUint64(ad[:8], uint64(key.FileID))
+	binary.BigEndian.PutUint64(ad[8:], uint64(key.From))
+	return ad[:]
+}
+
+// Seal encrypts content of chunk with given key by active key with new random nonce.
+// Returns given content as is with empty key ID if there is no active key.
+func (kr *Keyring) Seal(key ChunkKey, plain []byte) (kid string, nonce, data []byte, err error) {
+	kr.mux.RLock()
+	kid = kr.active
+	var aead = kr.aeads[kid]
+	kr.mux.RUnlock()
+	if aead == nil {
+		return "", nil, plain, nil
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	data = aead.Seal(nil, nonce, plain, chunkAD(key))
+	return
+}
+
+// Open decrypts content of chunk with given key sealed by key with given ID.
+// Returns content as is if key ID is empty.
+func (kr *Keyring) Open(key ChunkKey, kid string, nonce, data []byte) (plain []byte, err error) {
+	if kid == "" {
+		return data, nil
+	}
+	kr.mux.RLock()
+	var aead = kr.aeads[kid]
+	kr.mux.RUnlock()
+	if aead == nil {
+		err = fmt.Errorf("%w: '%s'", ErrKeyAbsent, kid)
+		return
+	}
+	return aead.Open(nil, nonce, data, chunkAD(key))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// LoadTestKeys loads random keys with given IDs to keyring, last key is active.
+func LoadTestKeys(t *testing.T, kr *Keyring, kids ...string) {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, kid := range kids {
+		var key = make([]byte, 32)
+		rand.Read(key)
+		buf.WriteString(kid + " " + base64.StdEncoding.EncodeToString(key) + "\n")
+	}
+	var fname = filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(fname, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(keyenv, "")
+	if err := kr.Load(fname); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSealedChunkPlace(t *testing.T) {
+	var kr Keyring
+	LoadTestKeys(t, &kr, "k1")
+	var plain = []byte("content of chunk")
+	var key = ChunkKey{FileID: 7, From: 4096}
+	var kid, nonce, data, err = kr.Seal(key, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid != "k1" || bytes.Contains(data, plain) {
+		t.Fatalf("chunk is not sealed")
+	}
+
+	var tests = []struct {
+		what string
+		key  ChunkKey
+		ok   bool
+	}{
+		{"original place", key, true},
+		{"other offset", ChunkKey{FileID: 7, From: 0}, false},
+		{"next offset", ChunkKey{FileID: 7, From: 4096 + int64(len(plain))}, false},
+		{"other file", ChunkKey{FileID: 8, From: 4096}, false},
+	}
+	for _, test := range tests {
+		var got, err = kr.Open(test.key, kid, nonce, data)
+		if test.ok && (err != nil || !bytes.Equal(got, plain)) {
+			t.Errorf("%s: chunk is not opened, error %v", test.what, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("%s: moved chunk is opened", test.what)
+		}
+	}
+}
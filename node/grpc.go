@@ -4,17 +4,13 @@ import (
 	"context"
 	"errors"
 	"io"
-	"sync"
+	"log/slog"
 	"time"
 
 	"github.com/schwarzlichtbezirk/dfs/pb"
-	"google.golang.org/grpc/grpclog"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-// Storage is singleton, files database with fileID/Chunk keys/values.
-var storage sync.Map
-
 var (
 	// ErrOutRange is "bounds out of the range" error message.
 	ErrOutRange = errors.New("bounds out of the range")
@@ -25,16 +21,38 @@ type routeDataGuideServer struct {
 	addr string
 }
 
+// Bounds returns range from start of first to end of last of given chunks,
+// or empty range if list is empty.
+func Bounds(list []*Entry) *pb.Range {
+	if len(list) == 0 {
+		return &pb.Range{}
+	}
+	var rng = &pb.Range{
+		NodeId: list[0].Range.NodeId,
+		FileId: list[0].Range.FileId,
+		From:   list[0].Range.From,
+		To:     list[0].Range.To,
+	}
+	for _, e := range list[1:] {
+		rng.From = min(rng.From, e.Range.From)
+		rng.To = max(rng.To, e.Range.To)
+	}
+	return rng
+}
+
 func (s *routeDataGuideServer) Read(ctx context.Context, arg *pb.Range) (res *pb.Chunk, err error) {
-	if data, ok := storage.Load(arg.FileId); ok {
-		var chunk = data.(*pb.Chunk)
-		if arg.From < chunk.Range.From || arg.To > chunk.Range.To {
+	if e := FindEntry(arg.FileId, arg.From); e != nil {
+		if arg.To > e.Range.To {
 			err = ErrOutRange
 			return
 		}
+		var val []byte
+		if val, err = e.Value(); err != nil {
+			return
+		}
 		res = &pb.Chunk{
 			Range: arg,
-			Value: chunk.Value[arg.From-chunk.Range.From : arg.To-chunk.Range.From],
+			Value: val[arg.From-e.Range.From : arg.To-e.Range.From],
 		}
 	} else {
 		res = &pb.Chunk{}
@@ -45,10 +63,27 @@ func (s *routeDataGuideServer) Read(ctx context.Context, arg *pb.Range) (res *pb
 func (s *routeDataGuideServer) Write(stream pb.DataGuide_WriteServer) error {
 	var count int32
 	var startTime = time.Now()
+	// received content is glued into chunks, and sealed at once at the end,
+	// next piece is glued to chunk of the same file if it continues chunk
+	var ranges = map[ChunkKey]*pb.Range{}
+	var values = map[ChunkKey][]byte{}
+	var stage = map[ChunkKey]bool{}
+	var last = map[int64]ChunkKey{} // last chunk of each file
 	for {
 		var chunk, err = stream.Recv()
 		if err == io.EOF {
-			grpclog.Infof("fetched %d items\n", count)
+			for key, rng := range ranges {
+				var e *Entry
+				if e, err = MakeEntry(rng, values[key]); err != nil {
+					return err
+				}
+				if stage[key] {
+					staged.Store(key, e)
+				} else {
+					StoreEntry(e)
+				}
+			}
+			slog.InfoContext(stream.Context(), "fetched items", "count", count)
 			var endTime = time.Now()
 			return stream.SendAndClose(&pb.Summary{
 				ChunkCount:  count,
@@ -59,11 +94,21 @@ func (s *routeDataGuideServer) Write(stream pb.DataGuide_WriteServer) error {
 			return err
 		}
 
-		var data, ok = storage.LoadOrStore(chunk.Range.FileId, chunk)
-		if ok {
-			var file = data.(*pb.Chunk)
-			file.Range.To += int64(len(chunk.Value))
-			file.Value = append(file.Value, chunk.Value...)
+		var fid = chunk.Range.FileId
+		if key, ok := last[fid]; ok && ranges[key].To == chunk.Range.From {
+			ranges[key].To += int64(len(chunk.Value))
+			values[key] = append(values[key], chunk.Value...)
+		} else {
+			key = Key(chunk.Range)
+			ranges[key] = &pb.Range{
+				NodeId: chunk.Range.NodeId,
+				FileId: fid,
+				From:   chunk.Range.From,
+				To:     chunk.Range.From + int64(len(chunk.Value)),
+			}
+			values[key] = append([]byte(nil), chunk.Value...)
+			stage[key] = chunk.Staged
+			last[fid] = key
 		}
 
 		count++
@@ -71,25 +116,99 @@ func (s *routeDataGuideServer) Write(stream pb.DataGuide_WriteServer) error {
 }
 
 func (s *routeDataGuideServer) GetRange(ctx context.Context, arg *pb.FileID) (res *pb.Range, err error) {
-	if data, ok := storage.Load(arg.Id); ok {
-		res = data.(*pb.Chunk).Range
+	if arg.From != nil {
+		if data, ok := storage.Load(ChunkKey{arg.Id, *arg.From}); ok {
+			res = data.(*Entry).Range
+			return
+		}
+		res = &pb.Range{}
 		return
 	}
-	res = &pb.Range{}
+	res = Bounds(FileEntries(arg.Id))
 	return
 }
 
 func (s *routeDataGuideServer) Remove(ctx context.Context, arg *pb.FileID) (res *pb.Range, err error) {
-	if data, ok := storage.LoadAndDelete(arg.Id); ok {
-		res = data.(*pb.Chunk).Range
+	if arg.From != nil {
+		var key = ChunkKey{arg.Id, *arg.From}
+		// staged chunk is removed on aborted upload
+		if data, ok := staged.LoadAndDelete(key); ok {
+			res = data.(*Entry).Range
+			return
+		}
+		if e := DeleteEntry(key); e != nil {
+			res = e.Range
+			return
+		}
+		res = &pb.Range{}
 		return
 	}
-	res = &pb.Range{}
+
+	var list []*Entry
+	staged.Range(func(key, value any) bool {
+		if key.(ChunkKey).FileID == arg.Id && staged.CompareAndDelete(key, value) {
+			list = append(list, value.(*Entry))
+		}
+		return true
+	})
+	for _, e := range FileEntries(arg.Id) {
+		if e = DeleteEntry(Key(e.Range)); e != nil {
+			list = append(list, e)
+		}
+	}
+	res = Bounds(list)
 	return
 }
 
 func (s *routeDataGuideServer) Purge(ctx context.Context, arg *emptypb.Empty) (res *emptypb.Empty, err error) {
-	storage = sync.Map{}
+	PurgeEntries()
 	res = &emptypb.Empty{}
 	return
 }
+
+func (s *routeDataGuideServer) Rotate(ctx context.Context, arg *emptypb.Empty) (res *emptypb.Empty, err error) {
+	if err = keyring.Load(cfg.KeyFile); err != nil {
+		return
+	}
+	slog.InfoContext(ctx, "encryption keys loaded", "count", keyring.Len(), "active", keyring.Active())
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		Rotate()
+	}()
+	res = &emptypb.Empty{}
+	return
+}
+
+func (s *routeDataGuideServer) Info(ctx context.Context, arg *emptypb.Empty) (res *pb.NodeInfo, err error) {
+	res = &pb.NodeInfo{
+		Id:        cfg.ID,
+		Version:   buildvers,
+		Builddate: builddate,
+		Protocol:  pb.Protocol,
+		Tier:      cfg.Tier,
+		Features:  []string{pb.FeatureHealth, pb.FeatureRotate, pb.FeatureList, pb.FeatureCommit, pb.FeatureChunks},
+	}
+	if keyring.Active() != "" {
+		res.Features = append(res.Features, pb.FeatureEncryption)
+	}
+	return
+}
+
+func (s *routeDataGuideServer) List(ctx context.Context, arg *emptypb.Empty) (res *pb.ChunkList, err error) {
+	res = &pb.ChunkList{}
+	storage.Range(func(key, value any) bool {
+		var e = value.(*Entry)
+		res.Chunks = append(res.Chunks, &pb.ChunkInfo{
+			Range:   e.Range,
+			Modtime: e.Time.UnixNano(),
+		})
+		return true
+	})
+	return
+}
+
+func (s *routeDataGuideServer) Commit(ctx context.Context, arg *pb.FileID) (res *pb.Range, err error) {
+	res = Bounds(CommitEntries(arg.Id, arg.From))
+	return
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// StartTestServer serves DataGuide at free local port with empty storage,
+// and returns client connected to it. Storage is purged on test cleanup.
+func StartTestServer(t *testing.T) pb.DataGuideClient {
+	t.Helper()
+	var lis, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server = grpc.NewServer()
+	pb.RegisterDataGuideServer(server, &routeDataGuideServer{addr: lis.Addr().String()})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	var conn *grpc.ClientConn
+	if conn, err = grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials())); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	PurgeEntries()
+	t.Cleanup(PurgeEntries)
+	return pb.NewDataGuideClient(conn)
+}
+
+// WriteChunks writes given pieces to node by one stream.
+func WriteChunks(t *testing.T, client pb.DataGuideClient, pieces ...*pb.Chunk) {
+	t.Helper()
+	var stream, err = client.Write(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, piece := range pieces {
+		if err = stream.Send(piece); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = stream.CloseAndRecv(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// content returns test content of file in given bounds.
+func content(fid, from, to int64) []byte {
+	var b = make([]byte, to-from)
+	for i := range b {
+		b[i] = byte(fid*31 + from + int64(i))
+	}
+	return b
+}
+
+// piece makes chunk of test file in given bounds.
+func piece(fid, from, to int64) *pb.Chunk {
+	return &pb.Chunk{
+		Range: &pb.Range{FileId: fid, From: from, To: to},
+		Value: content(fid, from, to),
+	}
+}
+
+func TestEncryptedChunks(t *testing.T) {
+	LoadTestKeys(t, &keyring, "k1")
+	t.Cleanup(func() { keyring.Load("") })
+	var client = StartTestServer(t)
+	var ctx = context.Background()
+
+	// file 7 has two separate chunks, first one is glued from two pieces
+	WriteChunks(t, client, piece(7, 0, 50), piece(7, 50, 100), piece(8, 0, 100))
+	WriteChunks(t, client, piece(7, 300, 400))
+	for _, key := range []ChunkKey{{7, 0}, {7, 300}, {8, 0}} {
+		var e = FindEntry(key.FileID, key.From)
+		if e == nil || e.KeyID != "k1" {
+			t.Fatalf("chunk %v is not sealed", key)
+		}
+	}
+
+	var tests = []struct {
+		what     string
+		fid      int64
+		from, to int64
+		ok       bool
+	}{
+		{"first chunk", 7, 0, 100, true},
+		{"glued pieces", 7, 10, 60, true},
+		{"second chunk", 7, 300, 400, true},
+		{"inside second chunk", 7, 320, 330, true},
+		{"other file", 8, 0, 100, true},
+		{"out of chunk", 7, 50, 150, false},
+		{"between chunks", 7, 150, 200, false},
+	}
+	for _, test := range tests {
+		var chunk, err = client.Read(ctx, &pb.Range{FileId: test.fid, From: test.from, To: test.to})
+		var ok = err == nil && bytes.Equal(chunk.Value, content(test.fid, test.from, test.to))
+		if ok != test.ok {
+			t.Errorf("%s: read [%d, %d) of file %d gives error %v", test.what, test.from, test.to, test.fid, err)
+		}
+	}
+
+	var from int64 = 300
+	var rng, err = client.GetRange(ctx, &pb.FileID{Id: 7, From: &from})
+	if err != nil || rng.From != 300 || rng.To != 400 {
+		t.Errorf("range of second chunk is [%d, %d), error %v", rng.GetFrom(), rng.GetTo(), err)
+	}
+	if rng, err = client.GetRange(ctx, &pb.FileID{Id: 7}); err != nil || rng.From != 0 || rng.To != 400 {
+		t.Errorf("range of file is [%d, %d), error %v", rng.GetFrom(), rng.GetTo(), err)
+	}
+
+	// chunk moved to other place can not be opened
+	var e = FindEntry(7, 300)
+	var moves = []struct {
+		what string
+		rng  *pb.Range
+	}{
+		{"moved to other offset", &pb.Range{FileId: 7, From: 100, To: 200}},
+		{"moved to other file", &pb.Range{FileId: 9, From: 300, To: 400}},
+	}
+	for _, move := range moves {
+		StoreEntry(&Entry{Range: move.rng, KeyID: e.KeyID, Nonce: e.Nonce, Data: e.Data, Time: e.Time})
+		if _, err = client.Read(ctx, move.rng); err == nil {
+			t.Errorf("%s: chunk is read", move.what)
+		}
+	}
+
+	// single chunk is removed, other chunk of file is kept
+	if rng, err = client.Remove(ctx, &pb.FileID{Id: 7, From: &from}); err != nil || rng.From != 300 {
+		t.Errorf("removed range is [%d, %d), error %v", rng.GetFrom(), rng.GetTo(), err)
+	}
+	if FindEntry(7, 300) != nil || FindEntry(7, 0) == nil {
+		t.Errorf("other chunks are changed by removal of one chunk")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics of node, exposed in Prometheus text format at "/metrics".
+var (
+	mtrStoredBytes = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "dfs",
+		Subsystem: "node",
+		Name:      "stored_bytes",
+		Help:      "Total size of chunks content stored on node.",
+	}, func() float64 {
+		var sum int64
+		storage.Range(func(key, value any) bool {
+			var e = value.(*Entry)
+			sum += e.Range.To - e.Range.From
+			return true
+		})
+		return float64(sum)
+	})
+	mtrChunks = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "dfs",
+		Subsystem: "node",
+		Name:      "chunks",
+		Help:      "Number of chunks stored on node.",
+	}, func() float64 {
+		var count int
+		storage.Range(func(key, value any) bool {
+			count++
+			return true
+		})
+		return float64(count)
+	})
+	mtrLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dfs",
+		Subsystem: "node",
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of gRPC calls by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(mtrStoredBytes, mtrChunks, mtrLatency)
+}
+
+// unaryLatency is server interceptor that measures latency of unary calls.
+func unaryLatency(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var t0 = time.Now()
+	var res, err = handler(ctx, req)
+	mtrLatency.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(t0).Seconds())
+	return res, err
+}
+
+// streamLatency is server interceptor that measures latency of streams.
+func streamLatency(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	var t0 = time.Now()
+	var err = handler(srv, ss)
+	mtrLatency.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(t0).Seconds())
+	return err
+}
+
+// RunMetrics starts HTTP listener with metrics, if its port is given.
+func RunMetrics() {
+	if cfg.PortMetrics == "" {
+		return
+	}
+	var server = &http.Server{
+		Addr:    cfg.PortMetrics,
+		Handler: promhttp.Handler(),
+	}
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+
+		slog.Info("metrics server starts", "addr", cfg.PortMetrics)
+		go func() {
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				telemetry.Fatal("failed to serve metrics", "addr", cfg.PortMetrics, "error", err)
+			}
+		}()
+
+		// wait for exit signal
+		<-exitctx.Done()
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error("shutdown metrics server", "addr", cfg.PortMetrics, "error", err)
+		} else {
+			slog.Info("metrics server closed", "addr", cfg.PortMetrics)
+		}
+	}()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Delays between failed registration attempts,
+// grows exponentially from minimum to maximum.
+const (
+	regMinDelay = time.Second
+	regMaxDelay = 30 * time.Second
+	regTimeout  = 5 * time.Second
+)
+
+// ErrRegPlain is "registry token can not be sent without TLS" error message.
+var ErrRegPlain = errors.New("registry token can not be sent without TLS")
+
+// ReadRegistryToken reads token presented to front registry. Token is
+// refused without TLS, else anyone on the path could take it and register
+// own node. Returns empty string if token file is not given.
+func ReadRegistryToken() (token string, err error) {
+	if cfg.RegTokenFile == "" {
+		return
+	}
+	var body []byte
+	if body, err = os.ReadFile(cfg.RegTokenFile); err != nil {
+		return
+	}
+	if certs == nil {
+		err = ErrRegPlain
+		return
+	}
+	token = strings.TrimSpace(string(body))
+	return
+}
+
+// RunRegistration registers node at front registry if front address
+// is given, and then sends heartbeats with period given by front
+// until exit signal. Node registers again if front has lost it.
+// If several fronts are given, node turns to next front on failure,
+// so it finds the leader of fronts cluster.
+func RunRegistration() {
+	if len(cfg.Front) == 0 {
+		return
+	}
+
+	var token, err = ReadRegistryToken()
+	if err != nil {
+		telemetry.Fatal("can not use registry token", "file", cfg.RegTokenFile, "error", err)
+	}
+
+	var creds = insecure.NewCredentials()
+	if certs != nil {
+		creds = credentials.NewTLS(certs.ClientConfig())
+	}
+	var conns = make([]*grpc.ClientConn, len(cfg.Front))
+	for i, addr := range cfg.Front {
+		var err error
+		if conns[i], err = grpc.NewClient(addr,
+			grpc.WithTransportCredentials(creds),
+			grpc.WithUnaryInterceptor(telemetry.UnaryClientRequestID),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		); err != nil {
+			telemetry.Fatal("can not connect to front registry", "addr", addr, "error", err)
+		}
+	}
+	var arg = &pb.Announce{
+		Id:   cfg.ID,
+		Addr: cfg.Addr,
+	}
+
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		var cur int // index of current front
+		var registered bool
+		var delay = regMinDelay
+		for {
+			var front = cfg.Front[cur]
+			var client = pb.NewRegistryClient(conns[cur])
+			var res *pb.Registration
+			var err error
+			var ctx, cancel = context.WithTimeout(exitctx, regTimeout)
+			if token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, pb.RegistryTokenKey, token)
+			}
+			if registered {
+				if res, err = client.Heartbeat(ctx, arg); status.Code(err) == codes.NotFound {
+					slog.Warn("front has lost registration", "front", front)
+					registered = false
+					cancel()
+					continue // register again at once
+				}
+			} else if res, err = client.Register(ctx, arg); err == nil {
+				slog.Info("registered at front", "front", front, "addr", cfg.Addr, "idx", res.NodeId)
+				registered = true
+			}
+			cancel()
+
+			var wait time.Duration
+			if err != nil {
+				if exitctx.Err() != nil {
+					return
+				}
+				slog.Warn("registry call failed", "front", front, "error", err, "retry", delay)
+				// try next front, and register at it
+				if len(cfg.Front) > 1 {
+					cur = (cur + 1) % len(cfg.Front)
+					registered = false
+				}
+				wait, delay = delay, min(delay*2, regMaxDelay)
+			} else {
+				wait, delay = time.Duration(res.Period), regMinDelay
+			}
+
+			select {
+			case <-exitctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/cert"
+)
+
+// LoadTestCerts loads node certificate signed by new CA.
+func LoadTestCerts(t *testing.T) *cert.Reloader {
+	t.Helper()
+	var dir = t.TempDir()
+	var write = func(name string, body []byte) string {
+		var fname = filepath.Join(dir, name)
+		if err := os.WriteFile(fname, body, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return fname
+	}
+	var caCert, caKey, err = cert.GenerateCA("test CA", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crt, key []byte
+	if crt, key, err = cert.GenerateCert(caCert, caKey, "node", []string{"localhost"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	var r *cert.Reloader
+	if r, err = cert.NewReloader(write("node.crt", crt), write("node.key", key), write("ca.crt", caCert)); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReadRegistryToken(t *testing.T) {
+	var saved, savedcerts = cfg, certs
+	t.Cleanup(func() { cfg, certs = saved, savedcerts })
+	var fname = filepath.Join(t.TempDir(), "regtoken.txt")
+	if err := os.WriteFile(fname, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var tlscerts = LoadTestCerts(t)
+
+	var tests = []struct {
+		what  string
+		file  string
+		certs *cert.Reloader
+		token string
+		err   error
+	}{
+		{"no token", "", nil, "", nil},
+		{"no token with TLS", "", tlscerts, "", nil},
+		{"token without TLS", fname, nil, "", ErrRegPlain},
+		{"token with TLS", fname, tlscerts, "secret", nil},
+	}
+	for _, test := range tests {
+		cfg.RegTokenFile, certs = test.file, test.certs
+		var token, err = ReadRegistryToken()
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.what, test.err, err)
+		}
+		if token != test.token {
+			t.Errorf("%s: got token %q, expected %q", test.what, token, test.token)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/schwarzlichtbezirk/dfs/pb"
+)
+
+// Entry is stored file chunk. Content is sealed by key with KeyID
+// and unique nonce, or is plain if KeyID is empty. Time is time of last
+// write to chunk. Entry is immutable, any change makes new entry.
+type Entry struct {
+	Range *pb.Range
+	KeyID string
+	Nonce []byte
+	Data  []byte
+	Time  time.Time
+}
+
+// ChunkKey is key of chunk in storage. Chunk is identified by ID of file
+// and offset of chunk in file, so file can have several chunks on node.
+type ChunkKey struct {
+	FileID int64
+	From   int64
+}
+
+// Key returns key of chunk with given range.
+func Key(rng *pb.Range) ChunkKey {
+	return ChunkKey{rng.FileId, rng.From}
+}
+
+// Storage is singleton, chunks database with ChunkKey/*Entry keys/values.
+var storage sync.Map
+
+// Staged is chunks written by staged upload with ChunkKey/*Entry keys/values.
+// They are moved to storage on commit, or expire if they are not committed.
+var staged sync.Map
+
+// files is index of stored chunks, it has sorted offsets of chunks
+// for each file ID. It's used to find chunk by offset in file.
+var files = map[int64][]int64{}
+
+// mutex for storage changes and files index access.
+var idxmux sync.RWMutex
+
+// MakeEntry seals given content of chunk by active key.
+func MakeEntry(rng *pb.Range, plain []byte) (e *Entry, err error) {
+	e = &Entry{
+		Range: rng,
+		Time:  time.Now(),
+	}
+	if e.KeyID, e.Nonce, e.Data, err = keyring.Seal(Key(rng), plain); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Value returns plain content of chunk.
+func (e *Entry) Value() ([]byte, error) {
+	return keyring.Open(Key(e.Range), e.KeyID, e.Nonce, e.Data)
+}
+
+// StoreEntry puts chunk to storage, it replaces stored chunk with the same key.
+func StoreEntry(e *Entry) {
+	idxmux.Lock()
+	defer idxmux.Unlock()
+	var key = Key(e.Range)
+	if _, ok := storage.Swap(key, e); !ok {
+		var froms = files[key.FileID]
+		var i, _ = slices.BinarySearch(froms, key.From)
+		files[key.FileID] = slices.Insert(froms, i, key.From)
+	}
+}
+
+// DeleteEntry removes chunk with given key from storage.
+// Returns removed chunk, or nil if it's absent.
+func DeleteEntry(key ChunkKey) *Entry {
+	idxmux.Lock()
+	defer idxmux.Unlock()
+	var data, ok = storage.LoadAndDelete(key)
+	if !ok {
+		return nil
+	}
+	var froms = files[key.FileID]
+	if i, found := slices.BinarySearch(froms, key.From); found {
+		froms = slices.Delete(froms, i, i+1)
+	}
+	if len(froms) > 0 {
+		files[key.FileID] = froms
+	} else {
+		delete(files, key.FileID)
+	}
+	return data.(*Entry)
+}
+
+// FileEntries returns all stored chunks of file ordered by offset.
+func FileEntries(fid int64) (list []*Entry) {
+	idxmux.RLock()
+	defer idxmux.RUnlock()
+	for _, from := range files[fid] {
+		if data, ok := storage.Load(ChunkKey{fid, from}); ok {
+			list = append(list, data.(*Entry))
+		}
+	}
+	return
+}
+
+// FindEntry returns stored chunk of file that contains given offset,
+// or nil if there is no such chunk.
+func FindEntry(fid, off int64) *Entry {
+	idxmux.RLock()
+	defer idxmux.RUnlock()
+	var froms = files[fid]
+	var i, found = slices.BinarySearch(froms, off)
+	if !found {
+		if i == 0 {
+			return nil
+		}
+		i-- // previous chunk can contain offset
+	}
+	if data, ok := storage.Load(ChunkKey{fid, froms[i]}); ok {
+		var e = data.(*Entry)
+		if off < e.Range.To || off == e.Range.From {
+			return e
+		}
+	}
+	return nil
+}
+
+// PurgeEntries deletes all stored and staged chunks.
+func PurgeEntries() {
+	idxmux.Lock()
+	defer idxmux.Unlock()
+	storage.Clear()
+	staged.Clear()
+	clear(files)
+}
+
+// CommitEntries moves staged chunks of file to storage. Chunk with given
+// offset is committed, or all chunks of file if offset is nil.
+// Returns committed chunks.
+func CommitEntries(fid int64, from *int64) (list []*Entry) {
+	if from != nil {
+		if data, ok := staged.LoadAndDelete(ChunkKey{fid, *from}); ok {
+			StoreEntry(data.(*Entry))
+			list = append(list, data.(*Entry))
+		}
+		return
+	}
+	staged.Range(func(key, value any) bool {
+		if key.(ChunkKey).FileID == fid && staged.CompareAndDelete(key, value) {
+			StoreEntry(value.(*Entry))
+			list = append(list, value.(*Entry))
+		}
+		return true
+	})
+	return
+}
+
+// ExpireStaged deletes staged chunks that were not committed during given time.
+func ExpireStaged(ttl time.Duration) {
+	var count int
+	var now = time.Now()
+	staged.Range(func(key, value any) bool {
+		if now.Sub(value.(*Entry).Time) > ttl && staged.CompareAndDelete(key, value) {
+			count++
+		}
+		return true
+	})
+	if count > 0 {
+		slog.Info("staged chunks expired", "count", count)
+	}
+}
+
+// RunExpiration deletes expired staged chunks with period of TTL until exit.
+func RunExpiration() {
+	exitwg.Add(1)
+	go func() {
+		defer exitwg.Done()
+		var ticker = time.NewTicker(cfg.StagedTTL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exitctx.Done():
+				return
+			case <-ticker.C:
+				ExpireStaged(cfg.StagedTTL)
+			}
+		}
+	}()
+}
+
+// rotating is set while re-encryption is in progress.
+var rotating atomic.Bool
+
+// Rotate re-encrypts all stored and staged chunks sealed by not active key.
+// Returns immediately if other rotation is in progress.
+func Rotate() {
+	if !rotating.CompareAndSwap(false, true) {
+		slog.Warn("keys rotation is already in progress")
+		return
+	}
+	defer rotating.Store(false)
+
+	var active = keyring.Active()
+	var count, fails int
+	for _, m := range []*sync.Map{&storage, &staged} {
+		m.Range(func(key, value any) bool {
+			// check on exit during rotation
+			select {
+			case <-exitctx.Done():
+				return false
+			default:
+			}
+
+			var old = value.(*Entry)
+			if old.KeyID == active {
+				return true
+			}
+			var val, err = old.Value()
+			if err != nil {
+				slog.Error("can not open chunk", "file", old.Range.FileId, "error", err)
+				fails++
+				return true
+			}
+			var e *Entry
+			if e, err = MakeEntry(old.Range, val); err != nil {
+				slog.Error("can not seal chunk", "file", old.Range.FileId, "error", err)
+				fails++
+				return true
+			}
+			e.Time = old.Time // re-encryption is not a write
+			// chunk could be changed by writer, so it's already sealed by active key
+			if m.CompareAndSwap(key, old, e) {
+				count++
+			}
+			return true
+		})
+	}
+	slog.Info("keys rotation complete", "re-encrypted", count, "failed", fails)
+}
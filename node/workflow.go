@@ -3,15 +3,22 @@ package main
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/schwarzlichtbezirk/dfs/cert"
 	"github.com/schwarzlichtbezirk/dfs/pb"
+	"github.com/schwarzlichtbezirk/dfs/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var (
@@ -20,16 +27,26 @@ var (
 	exitfn  context.CancelFunc
 	// wait group for all server goroutines
 	exitwg sync.WaitGroup
+	// TLS certificates for gRPC server, nil if TLS is off
+	certs *cert.Reloader
+	// flushes pending spans and stops tracing
+	tracingdone func(context.Context) error
 )
 
 func init() {
-	grpclog.SetLoggerV2(grpclog.NewLoggerV2(os.Stdout, os.Stderr, os.Stderr))
+	telemetry.SetupLog(os.Stdout, telemetry.FormatText, "info")
 }
 
 // Init performs global data initialization.
 func Init() {
-	grpclog.Infof("version: %s, builton: %s\n", buildvers, builddate)
-	grpclog.Infoln("starts")
+	ParseConfig()
+	if err := CheckConfig(); err != nil {
+		telemetry.Fatal("invalid configuration", "error", err)
+	}
+	if err := telemetry.SetupLog(os.Stdout, cfg.LogFormat, cfg.LogLevel); err != nil {
+		telemetry.Fatal("can not setup logging", "error", err)
+	}
+	slog.Info("starts", "id", cfg.ID, "version", buildvers, "builton", builddate)
 
 	// create context and wait the break
 	exitctx, exitfn = context.WithCancel(context.Background())
@@ -47,60 +64,131 @@ func Init() {
 		select {
 		case <-exitctx.Done():
 			if errors.Is(exitctx.Err(), context.DeadlineExceeded) {
-				grpclog.Infoln("shutting down by timeout")
+				slog.Info("shutting down by timeout")
 			} else if errors.Is(exitctx.Err(), context.Canceled) {
-				grpclog.Infoln("shutting down by cancel")
+				slog.Info("shutting down by cancel")
 			} else {
-				grpclog.Infof("shutting down by %s", exitctx.Err().Error())
+				slog.Info("shutting down", "reason", exitctx.Err())
 			}
 		case <-sigint:
-			grpclog.Infoln("shutting down by break")
+			slog.Info("shutting down by break")
 		case <-sigterm:
-			grpclog.Infoln("shutting down by process termination")
+			slog.Info("shutting down by process termination")
 		}
 		signal.Stop(sigint)
 		signal.Stop(sigterm)
 	}()
+
+	// load encryption keys
+	if err := keyring.Load(cfg.KeyFile); err != nil {
+		telemetry.Fatal("can not load encryption keys", "error", err)
+	}
+	if kid := keyring.Active(); kid != "" {
+		slog.Info("encryption keys loaded", "count", keyring.Len(), "active", kid)
+	} else {
+		slog.Warn("no encryption keys, chunks data will be stored as plain")
+	}
+
+	// setup tracing
+	var err error
+	if tracingdone, err = telemetry.Setup(exitctx, "dfs-node", buildvers, cfg.TraceExporter, cfg.TraceEndpoint); err != nil {
+		telemetry.Fatal("can not setup tracing", "error", err)
+	}
+	if cfg.TraceExporter != telemetry.ExporterNone {
+		slog.Info("spans are exported", "exporter", cfg.TraceExporter)
+	}
+
+	// load TLS certificates
+	if cfg.TLSCert != "" {
+		if certs, err = cert.NewReloader(cfg.TLSCert, cfg.TLSKey, cfg.TLSCA); err != nil {
+			telemetry.Fatal("can not load TLS certificates", "error", err)
+		}
+		if cfg.TLSCA != "" {
+			slog.Info("gRPC uses mutual TLS")
+		} else {
+			slog.Info("gRPC uses TLS")
+		}
+
+		// reload certificates on SIGHUP
+		go func() {
+			var sighup = make(chan os.Signal, 1)
+			signal.Notify(sighup, syscall.SIGHUP)
+			defer signal.Stop(sighup)
+			for {
+				select {
+				case <-exitctx.Done():
+					return
+				case <-sighup:
+					if err := certs.Reload(); err != nil {
+						slog.Error("can not reload TLS certificates", "error", err)
+					} else {
+						slog.Info("TLS certificates reloaded")
+					}
+				}
+			}
+		}()
+	} else {
+		slog.Warn("gRPC uses plain connections without TLS")
+	}
 }
 
 // Run launches server listeners.
 func Run() {
+	// starts metrics server
+	RunMetrics()
+	// starts deletion of not committed chunks
+	RunExpiration()
+
 	// starts gRPC servers
 	var grpcctx, grpccancel = context.WithCancel(context.Background())
 	exitwg.Add(1)
 	go func() {
 		defer exitwg.Done()
 
-		grpclog.Infof("grpc server %s starts\n", cfg.PortGRPC)
+		slog.Info("grpc server starts", "addr", cfg.PortGRPC)
 		var err error
 		var lis net.Listener
 		if lis, err = net.Listen("tcp", cfg.PortGRPC); err != nil {
-			grpclog.Fatalf("failed to listen: %v", err)
+			telemetry.Fatal("failed to listen", "addr", cfg.PortGRPC, "error", err)
+		}
+		var options = []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(telemetry.UnaryServerRequestID, unaryLatency),
+			grpc.ChainStreamInterceptor(telemetry.StreamServerRequestID, streamLatency),
+			grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		}
+		if certs != nil {
+			options = append(options, grpc.Creds(credentials.NewTLS(certs.ServerConfig())))
 		}
-		var server = grpc.NewServer()
+		var server = grpc.NewServer(options...)
 		pb.RegisterDataGuideServer(server, &routeDataGuideServer{addr: cfg.PortGRPC})
+		var hs = health.NewServer()
+		healthpb.RegisterHealthServer(server, hs)
 		go func() {
 			grpccancel()
 			if err := server.Serve(lis); err != nil {
-				grpclog.Fatalf("failed to serve: %v", err)
+				telemetry.Fatal("failed to serve", "addr", cfg.PortGRPC, "error", err)
 			}
 		}()
 
 		// wait for exit signal
 		<-exitctx.Done()
 
+		hs.Shutdown()
 		server.GracefulStop()
 
-		grpclog.Infof("grpc server %s closed\n", cfg.PortGRPC)
+		slog.Info("grpc server closed", "addr", cfg.PortGRPC)
 	}()
 
 	// wait until exit or service is ready
 	select {
 	case <-grpcctx.Done():
-		grpclog.Infoln("service ready")
+		slog.Info("service ready")
 	case <-exitctx.Done():
 		return
 	}
+
+	// register at front when server is ready
+	RunRegistration()
 }
 
 // Done performs graceful network shutdown,
@@ -110,5 +198,11 @@ func Done() {
 	<-exitctx.Done()
 	// wait until all server threads will be stopped.
 	exitwg.Wait()
-	grpclog.Infoln("shutting down complete.")
+	// flush pending spans
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracingdone(ctx); err != nil {
+		slog.Error("tracing shutdown", "error", err)
+	}
+	slog.Info("shutting down complete")
 }
@@ -34,6 +34,17 @@ type DataGuideClient interface {
 	Remove(ctx context.Context, in *FileID, opts ...grpc.CallOption) (*Range, error)
 	// Purge deleted all file chunks.
 	Purge(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Rotate reloads encryption keys and starts background re-encryption
+	// of all chunks sealed by not active keys.
+	Rotate(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Info returns node identity, build, protocol version and supported features.
+	Info(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeInfo, error)
+	// List returns information about all chunks stored on node.
+	List(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ChunkList, error)
+	// Commit makes staged chunk of file visible, chunk replaces
+	// stored chunk with the same file ID if it's present.
+	// Returns empty struct if no such staged chunk is present.
+	Commit(ctx context.Context, in *FileID, opts ...grpc.CallOption) (*Range, error)
 }
 
 type dataGuideClient struct {
@@ -114,6 +125,42 @@ func (c *dataGuideClient) Purge(ctx context.Context, in *emptypb.Empty, opts ...
 	return out, nil
 }
 
+func (c *dataGuideClient) Rotate(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/dfs.DataGuide/Rotate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataGuideClient) Info(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NodeInfo, error) {
+	out := new(NodeInfo)
+	err := c.cc.Invoke(ctx, "/dfs.DataGuide/Info", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataGuideClient) List(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ChunkList, error) {
+	out := new(ChunkList)
+	err := c.cc.Invoke(ctx, "/dfs.DataGuide/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataGuideClient) Commit(ctx context.Context, in *FileID, opts ...grpc.CallOption) (*Range, error) {
+	out := new(Range)
+	err := c.cc.Invoke(ctx, "/dfs.DataGuide/Commit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DataGuideServer is the server API for DataGuide service.
 // All implementations must embed UnimplementedDataGuideServer
 // for forward compatibility
@@ -129,6 +176,17 @@ type DataGuideServer interface {
 	Remove(context.Context, *FileID) (*Range, error)
 	// Purge deleted all file chunks.
 	Purge(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// Rotate reloads encryption keys and starts background re-encryption
+	// of all chunks sealed by not active keys.
+	Rotate(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// Info returns node identity, build, protocol version and supported features.
+	Info(context.Context, *emptypb.Empty) (*NodeInfo, error)
+	// List returns information about all chunks stored on node.
+	List(context.Context, *emptypb.Empty) (*ChunkList, error)
+	// Commit makes staged chunk of file visible, chunk replaces
+	// stored chunk with the same file ID if it's present.
+	// Returns empty struct if no such staged chunk is present.
+	Commit(context.Context, *FileID) (*Range, error)
 	mustEmbedUnimplementedDataGuideServer()
 }
 
@@ -151,6 +209,18 @@ func (UnimplementedDataGuideServer) Remove(context.Context, *FileID) (*Range, er
 func (UnimplementedDataGuideServer) Purge(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Purge not implemented")
 }
+func (UnimplementedDataGuideServer) Rotate(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rotate not implemented")
+}
+func (UnimplementedDataGuideServer) Info(context.Context, *emptypb.Empty) (*NodeInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedDataGuideServer) List(context.Context, *emptypb.Empty) (*ChunkList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedDataGuideServer) Commit(context.Context, *FileID) (*Range, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Commit not implemented")
+}
 func (UnimplementedDataGuideServer) mustEmbedUnimplementedDataGuideServer() {}
 
 // UnsafeDataGuideServer may be embedded to opt out of forward compatibility for this service.
@@ -262,6 +332,78 @@ func _DataGuide_Purge_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DataGuide_Rotate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataGuideServer).Rotate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfs.DataGuide/Rotate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataGuideServer).Rotate(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataGuide_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataGuideServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfs.DataGuide/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataGuideServer).Info(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataGuide_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataGuideServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfs.DataGuide/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataGuideServer).List(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataGuide_Commit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataGuideServer).Commit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfs.DataGuide/Commit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataGuideServer).Commit(ctx, req.(*FileID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DataGuide_ServiceDesc is the grpc.ServiceDesc for DataGuide service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -285,6 +427,22 @@ var DataGuide_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Purge",
 			Handler:    _DataGuide_Purge_Handler,
 		},
+		{
+			MethodName: "Rotate",
+			Handler:    _DataGuide_Rotate_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _DataGuide_Info_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _DataGuide_List_Handler,
+		},
+		{
+			MethodName: "Commit",
+			Handler:    _DataGuide_Commit_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -295,3 +453,133 @@ var DataGuide_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "dfs.proto",
 }
+
+// RegistryClient is the client API for Registry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RegistryClient interface {
+	// Register adds node with given address to cluster,
+	// or marks already known node as alive.
+	Register(ctx context.Context, in *Announce, opts ...grpc.CallOption) (*Registration, error)
+	// Heartbeat confirms that registered node is alive.
+	// Returns NotFound status if node is not registered.
+	Heartbeat(ctx context.Context, in *Announce, opts ...grpc.CallOption) (*Registration, error)
+}
+
+type registryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRegistryClient(cc grpc.ClientConnInterface) RegistryClient {
+	return &registryClient{cc}
+}
+
+func (c *registryClient) Register(ctx context.Context, in *Announce, opts ...grpc.CallOption) (*Registration, error) {
+	out := new(Registration)
+	err := c.cc.Invoke(ctx, "/dfs.Registry/Register", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) Heartbeat(ctx context.Context, in *Announce, opts ...grpc.CallOption) (*Registration, error) {
+	out := new(Registration)
+	err := c.cc.Invoke(ctx, "/dfs.Registry/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegistryServer is the server API for Registry service.
+// All implementations must embed UnimplementedRegistryServer
+// for forward compatibility
+type RegistryServer interface {
+	// Register adds node with given address to cluster,
+	// or marks already known node as alive.
+	Register(context.Context, *Announce) (*Registration, error)
+	// Heartbeat confirms that registered node is alive.
+	// Returns NotFound status if node is not registered.
+	Heartbeat(context.Context, *Announce) (*Registration, error)
+	mustEmbedUnimplementedRegistryServer()
+}
+
+// UnimplementedRegistryServer must be embedded to have forward compatible implementations.
+type UnimplementedRegistryServer struct {
+}
+
+func (UnimplementedRegistryServer) Register(context.Context, *Announce) (*Registration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedRegistryServer) Heartbeat(context.Context, *Announce) (*Registration, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedRegistryServer) mustEmbedUnimplementedRegistryServer() {}
+
+// UnsafeRegistryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RegistryServer will
+// result in compilation errors.
+type UnsafeRegistryServer interface {
+	mustEmbedUnimplementedRegistryServer()
+}
+
+func RegisterRegistryServer(s grpc.ServiceRegistrar, srv RegistryServer) {
+	s.RegisterService(&Registry_ServiceDesc, srv)
+}
+
+func _Registry_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Announce)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfs.Registry/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Register(ctx, req.(*Announce))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Announce)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dfs.Registry/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).Heartbeat(ctx, req.(*Announce))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Registry_ServiceDesc is the grpc.ServiceDesc for Registry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Registry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dfs.Registry",
+	HandlerType: (*RegistryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Registry_Register_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Registry_Heartbeat_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "dfs.proto",
+}
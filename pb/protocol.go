@@ -0,0 +1,39 @@
+package pb
+
+// Protocol is version of DataGuide protocol implemented by this package.
+// It's incremented on any incompatible change of service.
+const Protocol = 1
+
+// RegistryTokenKey is gRPC metadata key with shared token
+// presented by node to front registry.
+const RegistryTokenKey = "dfs-registry-token"
+
+// Optional features of node, announced by Info call.
+const (
+	FeatureHealth     = "health"     // standard gRPC health service
+	FeatureEncryption = "encryption" // chunks are encrypted at rest
+	FeatureRotate     = "rotate"     // encryption keys rotation
+	FeatureList       = "list"       // listing of stored chunks
+	FeatureCommit     = "commit"     // staged writes with commit
+	FeatureChunks     = "chunks"     // several chunks of file on node
+)
+
+// HasFeature returns true if given feature is present at node features list.
+func (x *NodeInfo) HasFeature(feature string) bool {
+	for _, f := range x.GetFeatures() {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// ChunkID returns identifier of chunk with given range,
+// that is ID of file and offset of chunk in file.
+func (x *Range) ChunkID() *FileID {
+	var from = x.GetFrom()
+	return &FileID{
+		Id:   x.GetFileId(),
+		From: &from,
+	}
+}
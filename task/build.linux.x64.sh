@@ -10,3 +10,4 @@ cp -ruv ./config/* $GOPATH/bin/config
 go env -w GOOS=linux GOARCH=amd64
 go build -o $GOPATH/bin/dfs.front.x64 -v -ldflags="-X 'main.buildvers=%buildvers%' -X 'main.builddate=%builddate%'" ./front
 go build -o $GOPATH/bin/dfs.node.x64 -v -ldflags="-X 'main.buildvers=%buildvers%' -X 'main.builddate=%builddate%'" ./node
+go build -o $GOPATH/bin/dfs.ctl.x64 -v ./ctl
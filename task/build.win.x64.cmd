@@ -10,3 +10,4 @@ xcopy .\config %GOPATH%\bin\config /f /d /i /e /k /y
 go env -w GOOS=windows GOARCH=amd64
 go build -o %GOPATH%/bin/dfs.front.x64.exe -v -ldflags="-X 'main.buildvers=%buildvers%' -X 'main.builddate=%builddate%'" ./front
 go build -o %GOPATH%/bin/dfs.node.x64.exe -v -ldflags="-X 'main.buildvers=%buildvers%' -X 'main.builddate=%builddate%'" ./node
+go build -o %GOPATH%/bin/dfs.ctl.x64.exe -v ./ctl
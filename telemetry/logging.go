@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+// Log output formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ErrLogFormat is "unknown log format" error message.
+var ErrLogFormat = errors.New("unknown log format, expected 'text' or 'json'")
+
+// SetupLog installs default slog logger that writes records in given format
+// to given writer, with given minimum level. Records made with context
+// are supplemented with request ID of this context. gRPC internal logs
+// are also redirected to this logger.
+func SetupLog(w io.Writer, format, level string) (err error) {
+	var lvl slog.Level
+	if level != "" {
+		if err = lvl.UnmarshalText([]byte(level)); err != nil {
+			return
+		}
+	}
+	var opts = &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch format {
+	case FormatText, "":
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return ErrLogFormat
+	}
+	var logger = slog.New(ctxHandler{h})
+	slog.SetDefault(logger)
+	grpclog.SetLoggerV2(grpcLogger{logger.With("system", "grpc")})
+	return
+}
+
+// Fatal logs given message with error level and exits the program.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// ctxHandler is slog handler that adds request ID taken from context to records.
+type ctxHandler struct {
+	slog.Handler
+}
+
+// Handle is slog.Handler interface implementation.
+func (h ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs is slog.Handler interface implementation.
+func (h ctxHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ctxHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup is slog.Handler interface implementation.
+func (h ctxHandler) WithGroup(name string) slog.Handler {
+	return ctxHandler{h.Handler.WithGroup(name)}
+}
+
+// grpcLogger is grpclog.LoggerV2 implementation that writes to slog logger.
+// gRPC informational messages are written with debug level.
+type grpcLogger struct {
+	*slog.Logger
+}
+
+func (l grpcLogger) Info(args ...any)                    { l.Debug(fmt.Sprint(args...)) }
+func (l grpcLogger) Infoln(args ...any)                  { l.Debug(fmt.Sprint(args...)) }
+func (l grpcLogger) Infof(format string, args ...any)    { l.Debug(fmt.Sprintf(format, args...)) }
+func (l grpcLogger) Warning(args ...any)                 { l.Warn(fmt.Sprint(args...)) }
+func (l grpcLogger) Warningln(args ...any)               { l.Warn(fmt.Sprint(args...)) }
+func (l grpcLogger) Warningf(format string, args ...any) { l.Warn(fmt.Sprintf(format, args...)) }
+func (l grpcLogger) Error(args ...any)                   { l.Logger.Error(fmt.Sprint(args...)) }
+func (l grpcLogger) Errorln(args ...any)                 { l.Logger.Error(fmt.Sprint(args...)) }
+func (l grpcLogger) Errorf(format string, args ...any)   { l.Logger.Error(fmt.Sprintf(format, args...)) }
+func (l grpcLogger) Fatal(args ...any)                   { l.fatal(fmt.Sprint(args...)) }
+func (l grpcLogger) Fatalln(args ...any)                 { l.fatal(fmt.Sprint(args...)) }
+func (l grpcLogger) Fatalf(format string, args ...any)   { l.fatal(fmt.Sprintf(format, args...)) }
+
+func (l grpcLogger) fatal(msg string) {
+	l.Logger.Error(msg)
+	os.Exit(1)
+}
+
+// V reports whether verbosity level is enabled, only level 0 is enabled.
+func (l grpcLogger) V(level int) bool { return level <= 0 }
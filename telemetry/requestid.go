@@ -0,0 +1,88 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Request ID transport keys.
+const (
+	RequestIDHeader = "X-Request-ID" // HTTP header
+	RequestIDMeta   = "x-request-id" // gRPC metadata key
+)
+
+// MaxRequestIDLen is maximum length of request ID accepted from client.
+const MaxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// NewRequestID returns new random request ID.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID returns copy of context with given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns request ID attached to context, or empty string.
+func RequestID(ctx context.Context) string {
+	var id, _ = ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// outgoing returns context with request ID placed to outgoing gRPC metadata.
+func outgoing(ctx context.Context) context.Context {
+	if id := RequestID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, RequestIDMeta, id)
+	}
+	return ctx
+}
+
+// incoming returns context with request ID taken from incoming gRPC metadata.
+func incoming(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDMeta); len(v) > 0 && v[0] != "" {
+			return WithRequestID(ctx, v[0])
+		}
+	}
+	return ctx
+}
+
+// UnaryClientRequestID is client interceptor that passes request ID to server.
+func UnaryClientRequestID(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientRequestID is client interceptor that passes request ID to server.
+func StreamClientRequestID(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoing(ctx), desc, cc, method, opts...)
+}
+
+// UnaryServerRequestID is server interceptor that attaches request ID given by client to context.
+func UnaryServerRequestID(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(incoming(ctx), req)
+}
+
+// StreamServerRequestID is server interceptor that attaches request ID given by client to context.
+func StreamServerRequestID(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &idStream{ss, incoming(ss.Context())})
+}
+
+// idStream is server stream with context that carries request ID.
+type idStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context is grpc.ServerStream interface implementation.
+func (s *idStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,78 @@
+// Package telemetry provides OpenTelemetry tracing setup for services,
+// with spans export to stdout or to OTLP collector.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters of spans.
+const (
+	ExporterNone   = ""       // spans are not recorded
+	ExporterStdout = "stdout" // spans are written to stdout as JSON lines
+	ExporterOTLP   = "otlp"   // spans are sent to OTLP collector over gRPC
+)
+
+// ErrExporter is "unknown spans exporter" error message.
+var ErrExporter = errors.New("unknown spans exporter, expected 'stdout' or 'otlp'")
+
+// Setup installs global tracer provider for service with given name and
+// version, that exports spans by given exporter. Endpoint is address of
+// OTLP collector in "host:port" form for TLS connection, or URL with
+// "http://" scheme for plain connection. If endpoint is empty, it's taken
+// from OTEL_EXPORTER_OTLP_ENDPOINT environment variable, or localhost:4317.
+// W3C trace context propagation is installed in any case, so service
+// passes trace context through even if spans are not exported.
+// Returns function that flushes pending spans and stops exporter.
+func Setup(ctx context.Context, service, version, exporter, endpoint string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		if exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout)); err != nil {
+			return
+		}
+	case ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if strings.Contains(endpoint, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+		} else if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if exp, err = otlptracegrpc.New(ctx, opts...); err != nil {
+			return
+		}
+	default:
+		err = ErrExporter
+		return
+	}
+
+	var res *resource.Resource
+	if res, err = resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(service),
+		semconv.ServiceVersion(version),
+	)); err != nil {
+		return
+	}
+	var tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}